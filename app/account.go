@@ -0,0 +1,128 @@
+package app
+
+import (
+	"archive/zip"
+	"fmt"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"gorm.io/gorm"
+)
+
+// RegisterAccountData adds the data the app stores for a user to the
+// personal data export and deletes it together with the account.
+func RegisterAccountData() {
+	// The reviews reference the entries and the entries the decks, so
+	// they are purged in this order.
+	auth.RegisterAccountData(auth.AccountData{
+		Export: exportReviews,
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Delete(&types.Review{}, "user_id = ?", userID).Error
+		},
+	})
+	auth.RegisterAccountData(auth.AccountData{
+		Export: exportEntries,
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Unscoped().Delete(&types.GermanWord{}, "user_id = ?", userID).Error
+		},
+	})
+	auth.RegisterAccountData(auth.AccountData{
+		Export: exportDecks,
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Unscoped().Delete(&types.Deck{}, "user_id = ?", userID).Error
+		},
+	})
+	auth.RegisterAccountData(auth.AccountData{
+		Export: exportFailedUploads,
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Delete(&types.FailedUpload{}, "user_id = ?", userID).Error
+		},
+	})
+	auth.RegisterAccountData(auth.AccountData{
+		Export: exportAIUsage,
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Delete(&types.AIUsage{}, "user_id = ?", userID).Error
+		},
+	})
+}
+
+func exportEntries(userID uint, archive *zip.Writer) error {
+	var words []types.GermanWord
+	if err := db.Get().Where("user_id = ?", userID).Order("id").Find(&words).Error; err != nil {
+		return err
+	}
+	entries := make([]map[string]any, len(words))
+	for i, word := range words {
+		entries[i] = map[string]any{
+			"id":         word.ID,
+			"word":       word.GermanWord,
+			"definition": word.Definition,
+			"example":    word.Example,
+			"prompt":     word.PromptVersion,
+			"deck_id":    word.DeckID,
+			"due_at":     word.DueAt,
+			"created_at": word.CreatedAt,
+			"updated_at": word.UpdatedAt,
+		}
+	}
+	return auth.WriteJSON(archive, "entries.json", entries)
+}
+
+func exportDecks(userID uint, archive *zip.Writer) error {
+	var decks []types.Deck
+	if err := db.Get().Where("user_id = ?", userID).Order("id").Find(&decks).Error; err != nil {
+		return err
+	}
+	res := make([]map[string]any, len(decks))
+	for i, deck := range decks {
+		res[i] = map[string]any{
+			"id":          deck.ID,
+			"name":        deck.Name,
+			"description": deck.Description,
+			"created_at":  deck.CreatedAt,
+			"updated_at":  deck.UpdatedAt,
+		}
+	}
+	return auth.WriteJSON(archive, "decks.json", res)
+}
+
+func exportReviews(userID uint, archive *zip.Writer) error {
+	var reviews []types.Review
+	if err := db.Get().Where("user_id = ?", userID).Order("id").Find(&reviews).Error; err != nil {
+		return err
+	}
+	return auth.WriteJSON(archive, "reviews.json", reviews)
+}
+
+func exportFailedUploads(userID uint, archive *zip.Writer) error {
+	var uploads []types.FailedUpload
+	if err := db.Get().Where("user_id = ?", userID).Order("id").Find(&uploads).Error; err != nil {
+		return err
+	}
+	if err := auth.WriteJSON(archive, "failed_uploads.json", uploads); err != nil {
+		return err
+	}
+	// The pictures are the user's too, named by the ID of the upload.
+	for _, upload := range uploads {
+		if len(upload.Image) == 0 {
+			continue
+		}
+		w, err := archive.Create(fmt.Sprintf("failed_uploads/%d", upload.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(upload.Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportAIUsage(userID uint, archive *zip.Writer) error {
+	var usage []types.AIUsage
+	if err := db.Get().Where("user_id = ?", userID).Order("id").Find(&usage).Error; err != nil {
+		return err
+	}
+	return auth.WriteJSON(archive, "ai_usage.json", usage)
+}
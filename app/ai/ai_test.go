@@ -1,56 +1,33 @@
 package ai
 
 import (
-	"fmt"
-	"log"
-	"os"
 	"smartquiz/app/db"
+	"smartquiz/app/dbtest"
 	"smartquiz/app/types"
 	"testing"
+	"time"
 )
 
-// Encode image to base64
-func fileToBytes(image_path string) []byte {
-	file, err := os.Open(image_path)
-	if err != nil {
-		log.Fatalf("Failed to open file %v\n", err)
-	}
-	defer file.Close()
-	fs, _ := file.Stat()
-	size := fs.Size()
-	fmt.Println(size)
-	buffer := make([]byte, size)
-	file.Read(buffer)
-	return buffer
-}
-
-const ImagePath = `C:\Users\qxz2fxf\Downloads\2024-09-15 21_31_30-glossary _ Übersetzung Englisch-Deutsch.png`
-
-// only works in windows...
-// func TestVision(t *testing.T) {
-// 	visionRes := ReadPicture(fileToBytes(ImagePath))
-// 	fmt.Printf("Json succesfully read.\n %v\n%v\n%v", visionRes.Glossary, visionRes.Definition, visionRes.Example)
-// 	germanWord := types.GermanWord{
-// 		DifficultyLevel: "",
-// 		GermanWord:      visionRes.Glossary,
-// 		Definition:      visionRes.Definition,
-// 	}
-// 	err := db.Get().Save(&germanWord).Error
-// 	if err != nil {
-// 		fmt.Println("error when saving glossary to database", err)
-// 		t.Fail()
-// 	}
-// }
-
 func TestDatabase(t *testing.T) {
+	database := dbtest.New(t)
+	// Postgres and MySQL check that the user exists.
+	user := map[string]any{
+		"email": "test@example.com", "password_hash": "", "first_name": "", "last_name": "",
+		"created_at": time.Now(), "updated_at": time.Now(),
+	}
+	if err := database.Table("users").Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	var userID uint
+	database.Table("users").Select("id").Where("email = ?", "test@example.com").Scan(&userID)
 	germanWord := types.GermanWord{
+		UserID:     userID,
 		Example:    "",
 		GermanWord: "test-word",
 		Definition: "test-definition",
 	}
 	err := db.Get().Save(&germanWord).Error
 	if err != nil {
-		fmt.Println("error when saving glossary to database", err)
-		t.Fail()
+		t.Fatal("error when saving glossary to database", err)
 	}
 }
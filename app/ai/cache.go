@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+)
+
+// cacheTTLFromConfig returns AI_CACHE_TTL_IN_HOURS, 0 disables the cache.
+func cacheTTLFromConfig() time.Duration {
+	return time.Duration(conf.Get().AI.CacheTTLInHours) * time.Hour
+}
+
+// CacheStats counts the cache hits and misses, published as "ai_cache"
+// on /debug/vars. They are also on /metrics, see countCache.
+var CacheStats = expvar.NewMap("ai_cache")
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context that makes the Cache skip the lookup
+// and always call the provider, for example to regenerate an entry. The
+// fresh response still replaces the cached one.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// Cache is a Provider that stores the responses of the wrapped provider
+// in the database, so identical requests are only paid for once.
+// Requests are identical if they go to the same provider, model and
+// prompt version with the same input.
+type Cache struct {
+	Provider Provider
+	// TTL of the cached responses, zero disables the cache.
+	TTL time.Duration
+}
+
+func NewCache(p Provider, ttl time.Duration) *Cache {
+	return &Cache{Provider: p, TTL: ttl}
+}
+
+func (c *Cache) Name() string {
+	return c.Provider.Name()
+}
+
+func (c *Cache) Check() error {
+	return CheckProvider(c.Provider)
+}
+
+func (c *Cache) Vision(ctx context.Context, req VisionRequest) (VisionResponse, error) {
+	if c.TTL <= 0 {
+		return c.Provider.Vision(ctx, req)
+	}
+
+	key := c.key(req)
+	if !cacheBypassed(ctx) {
+		var res VisionResponse
+		ok, err := c.get(ctx, key, &res)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read the ai cache", "err", err)
+		}
+		if ok {
+			countCache("hits")
+			res.Cached = true
+			return res, nil
+		}
+		countCache("misses")
+	} else {
+		countCache("bypassed")
+	}
+
+	res, err := c.Provider.Vision(ctx, req)
+	if err != nil {
+		return res, err
+	}
+	if err := c.set(ctx, key, req, res); err != nil {
+		slog.ErrorContext(ctx, "failed to write the ai cache", "err", err)
+	}
+	return res, nil
+}
+
+func (c *Cache) key(req VisionRequest) string {
+	input := sha256.Sum256(req.Image)
+	h := sha256.New()
+	for _, part := range []string{c.Provider.Name(), req.Model, req.PromptVersion, hex.EncodeToString(input[:])} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) get(ctx context.Context, key string, v any) (bool, error) {
+	var entry types.AICacheEntry
+	// key is a reserved word in MySQL, a map condition quotes it.
+	res := db.Get().WithContext(ctx).Where(map[string]any{"key": key}).Where("expires_at > ?", time.Now()).Limit(1).Find(&entry)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	return true, json.Unmarshal(entry.Response, v)
+}
+
+func (c *Cache) set(ctx context.Context, key string, req VisionRequest, res VisionResponse) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	entry := types.AICacheEntry{
+		Key:           key,
+		Provider:      c.Provider.Name(),
+		Model:         req.Model,
+		PromptVersion: req.PromptVersion,
+		Response:      b,
+		ExpiresAt:     time.Now().Add(c.TTL),
+	}
+	return db.Get().WithContext(ctx).Save(&entry).Error
+}
+
+// CleanupCache deletes expired cache entries every interval until the
+// context is cancelled.
+func CleanupCache(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res := db.Get().Delete(&types.AICacheEntry{}, "expires_at <= ?", time.Now())
+		if res.Error != nil {
+			slog.Error("failed to delete expired ai cache entries", "err", res.Error)
+		} else if res.RowsAffected > 0 {
+			slog.Info("deleted expired ai cache entries", "count", res.RowsAffected)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
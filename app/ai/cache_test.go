@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) Vision(ctx context.Context, req VisionRequest) (VisionResponse, error) {
+	p.calls++
+	return VisionResponse{
+		Model:   req.Model,
+		Usage:   Usage{TotalTokens: 10},
+		Choices: []Choice{{Message: Message{Content: `{"Glossary":"Haus"}`}}},
+	}, nil
+}
+
+func TestCache(t *testing.T) {
+	dbtest.New(t)
+	p := &countingProvider{}
+	cache := NewCache(p, time.Hour)
+	ctx := context.Background()
+	req := VisionRequest{Model: "m", PromptVersion: "v1", Image: []byte(time.Now().String())}
+
+	res, err := cache.Vision(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Cached || p.calls != 1 {
+		t.Fatalf("expected a miss, got cached=%v calls=%d", res.Cached, p.calls)
+	}
+
+	res, err = cache.Vision(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Cached || p.calls != 1 || res.Choices[0].Message.Content != `{"Glossary":"Haus"}` {
+		t.Fatalf("expected a hit, got cached=%v calls=%d", res.Cached, p.calls)
+	}
+
+	if _, err := cache.Vision(WithoutCache(ctx), req); err != nil {
+		t.Fatal(err)
+	}
+	if p.calls != 2 {
+		t.Fatalf("expected the bypass to call the provider, got %d calls", p.calls)
+	}
+
+	req.PromptVersion = "v2"
+	if res, _ := cache.Vision(ctx, req); res.Cached {
+		t.Fatal("expected a new prompt version to miss the cache")
+	}
+}
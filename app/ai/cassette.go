@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoCassette is returned by a replaying Cassette for a request that
+// was never recorded.
+var ErrNoCassette = errors.New("no recorded response")
+
+// Cassette is a Provider that replays responses recorded in a directory,
+// so extraction can be tested without calling a model. If Provider is
+// set the cassette records instead: every request is sent to it and the
+// response is written to the directory.
+//
+// Responses are stored by model, prompt text and image. A changed
+// prompt therefore needs new recordings.
+type Cassette struct {
+	Dir string
+	// Provider records new responses if set.
+	Provider Provider
+}
+
+type cassetteEntry struct {
+	Model         string         `json:"model"`
+	PromptVersion string         `json:"prompt_version"`
+	Image         string         `json:"image_sha256"`
+	Response      VisionResponse `json:"response"`
+}
+
+// NewCassette returns a Cassette replaying the responses in dir.
+func NewCassette(dir string) *Cassette {
+	return &Cassette{Dir: dir}
+}
+
+func (c *Cassette) Name() string {
+	return "cassette"
+}
+
+func (c *Cassette) Vision(ctx context.Context, req VisionRequest) (VisionResponse, error) {
+	image := sha256.Sum256(req.Image)
+	path := filepath.Join(c.Dir, c.key(req)+".json")
+	if c.Provider != nil {
+		res, err := c.Provider.Vision(ctx, req)
+		if err != nil {
+			return res, err
+		}
+		entry := cassetteEntry{
+			Model:         req.Model,
+			PromptVersion: req.PromptVersion,
+			Image:         hex.EncodeToString(image[:]),
+			Response:      res,
+		}
+		b, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return res, err
+		}
+		return res, os.WriteFile(path, append(b, '\n'), 0o644)
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return VisionResponse{}, fmt.Errorf("%w for %s with prompt %s", ErrNoCassette, hex.EncodeToString(image[:8]), req.PromptVersion)
+	}
+	if err != nil {
+		return VisionResponse{}, err
+	}
+	var entry cassetteEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return VisionResponse{}, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return entry.Response, nil
+}
+
+func (c *Cassette) key(req VisionRequest) string {
+	image := sha256.Sum256(req.Image)
+	h := sha256.New()
+	for _, part := range []string{req.Model, req.Prompt, hex.EncodeToString(image[:])} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
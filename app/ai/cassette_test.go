@@ -0,0 +1,36 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCassette(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	req := VisionRequest{Model: "m", Prompt: "read the picture", PromptVersion: "v1", Image: []byte("image")}
+
+	if _, err := NewCassette(dir).Vision(ctx, req); !errors.Is(err, ErrNoCassette) {
+		t.Fatalf("expected ErrNoCassette before recording, got %v", err)
+	}
+
+	p := &countingProvider{}
+	recorder := &Cassette{Dir: dir, Provider: p}
+	if _, err := recorder.Vision(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewCassette(dir).Vision(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.calls != 1 || res.Choices[0].Message.Content != `{"Glossary":"Haus"}` {
+		t.Fatalf("expected the recorded response to be replayed, got %+v after %d calls", res, p.calls)
+	}
+
+	req.Prompt = "a changed prompt"
+	if _, err := NewCassette(dir).Vision(ctx, req); !errors.Is(err, ErrNoCassette) {
+		t.Fatalf("expected a changed prompt to need a new recording, got %v", err)
+	}
+}
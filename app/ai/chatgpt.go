@@ -1,17 +1,23 @@
 package ai
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"os/user"
-	"path/filepath"
-	"runtime/debug"
+	"log/slog"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/pkg/tracing"
 
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -27,46 +33,8 @@ type Json struct {
 	Glossary   string
 	Definition string
 	Example    string
-}
-
-func ReadJsonBytes(jsonBytes []byte) Json {
-	var res Json
-	err := json.Unmarshal(jsonBytes, &res)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-	fmt.Printf("%+v", res)
-	return res
-}
-
-func ReadJsonString(json_string string) Json {
-	// Function that pases json string
-	var res Json
-	err := json.Unmarshal([]byte(json_string), &res)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return res
-}
-
-// The api key should be saved in a text file in the user's home directory
-func getAPIKey() string {
-	usr, err := user.Current()
-	if err != nil {
-		debug.PrintStack()
-		log.Fatalf("Error getting user's home directory: %v", err)
-	}
-	filePath := filepath.Join(usr.HomeDir, ".api_key.txt")
-	apiKeyBytes, err := os.ReadFile(filePath)
-	if err != nil {
-		debug.PrintStack()
-		log.Fatalf("Failed to read api key %v\n", err)
-	}
-	return string(apiKeyBytes)
-}
-
-func getEnvAPIKey() string {
-	return os.Getenv("API_KEY")
+	// PromptVersion is the ID of the prompt the JSON was generated with.
+	PromptVersion string `json:"-"`
 }
 
 type Choice struct {
@@ -89,51 +57,41 @@ type Usage struct {
 type VisionResponse struct {
 	Id      string   `json:"id"`
 	Object  string   `json:"object"`
-	Created string   `json:"created"`
+	Created int64    `json:"created"`
 	Model   string   `json:"model"`
 	Usage   Usage    `json:"usage"`
 	Choices []Choice `json:"choices"`
+	// Cached is true if the response was served from the cache and
+	// didn't cost anything.
+	Cached bool `json:"-"`
 }
 
-// TODO delete these two functions not used
-// Encode image to base64
-func encodeImage(image_path string) string {
-	file, err := os.Open(image_path)
-	if err != nil {
-		log.Fatalf("Failed to open file %v\n", err)
-	}
-	defer file.Close()
-	fs, _ := file.Stat()
-	size := fs.Size()
-	fmt.Println(size)
-	buffer := make([]byte, size)
-	file.Read(buffer)
-	str := base64.StdEncoding.EncodeToString(buffer)
-	return str
+func CallVisionApi(question string, image []byte) (VisionResponse, error) {
+	return callVisionApi(context.Background(), modelVision, question, image)
 }
 
-// Encode image to base64
-func encodeImageBytes(picture []byte) string {
-	str := base64.StdEncoding.EncodeToString(picture)
-	return str
-}
+func callVisionApi(ctx context.Context, model string, question string, image []byte) (_ VisionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "POST "+apiEndpointChat, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String("POST"), semconv.URLFull(apiEndpointChat)))
+	defer func() { tracing.End(span, err) }()
+	headers := propagation.HeaderCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
 
-func CallVisionApi(question string, image []byte) VisionResponse {
-	fmt.Println("Calling vision API")
-	fmt.Println("api endpoint: ", apiEndpointChat)
+	slog.DebugContext(ctx, "calling vision api", "model", model, "image_size", len(image))
 	client := resty.New()
 	response, err := client.R().
-		SetAuthToken(os.Getenv("API_KEY")).
+		SetContext(ctx).
+		SetHeaderMultiValues(headers).
+		SetAuthToken(conf.Get().AI.APIKey).
 		SetHeader("Content-Type", "application/json").
 		SetBody(map[string]interface{}{
-			"model": modelVision,
+			"model": model,
 			"messages": []map[string]interface{}{
 				{
 					"role": "user",
 					"content": []map[string]interface{}{
 						{"type": "text", "text": question},
 						{"type": "image_url", "image_url": map[string]string{
-							// "url": "data:image/jpeg;base64," + encodeImage(image_path),
 							"url": "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
 						}},
 					},
@@ -142,84 +100,93 @@ func CallVisionApi(question string, image []byte) VisionResponse {
 			"max_tokens": 1000,
 		}).
 		Post(apiEndpointChat)
+	if response != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode()))
+	}
 	if err != nil {
-		log.Fatalf("Failed to send request %v\n %v\n", err, response)
+		return VisionResponse{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	if response.IsError() {
+		return VisionResponse{}, fmt.Errorf("vision api returned %s: %s", response.Status(), response.Body())
 	}
-	fmt.Println("raw response: ", response)
 	var parsed_response VisionResponse
-	json.Unmarshal(response.Body(), &parsed_response)
-	fmt.Println("raw response: ", parsed_response)
-	return parsed_response
+	if err := json.Unmarshal(response.Body(), &parsed_response); err != nil {
+		return VisionResponse{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return parsed_response, nil
 }
 
-func CallVisionApi_notworking(question string, image []byte) VisionResponse {
-	fmt.Println("Calling vision API")
-	fmt.Println("image bytes: ", image)
-	fmt.Println("api key from env: ", os.Getenv("API_KEY"))
-	fmt.Println("model: ", modelVision)
-	fmt.Println("promt: ", question)
-	fmt.Println("api endpoint: ", apiEndpointChat)
-
-	client := resty.New()
+// ReadPictureData is the data of the read_picture prompt.
+type ReadPictureData struct {
+	MaxExampleSentences int
+}
 
-	// Use a multipart form to send the image and text
-	response, err := client.R().
-		SetAuthToken(os.Getenv("API_KEY")).
-		SetFileReader("file", "image.png", bytes.NewReader(image)). // Pass the image as a file
-		SetFormData(map[string]string{
-			"model":  modelVision,
-			"prompt": question, // Use 'prompt' or 'messages' depending on API
-		}).
-		Post(apiEndpointChat)
+// PictureRequest asks ReadPicture to extract the word of an image.
+type PictureRequest struct {
+	// UserID the usage is recorded for, see CheckQuota.
+	UserID uint
+	Image  []byte
+	// Language of the definition and example, DefaultLanguage if empty.
+	Language string
+}
 
-	if err != nil {
-		log.Fatalf("Failed to send request: %v\n", err)
+// ReadPicture extracts the word of interest from the picture with the
+// read_picture prompt. Identical pictures are served from the cache
+// unless ctx is WithoutCache.
+func ReadPicture(ctx context.Context, req PictureRequest) (_ Json, err error) {
+	ctx, span := tracing.Start(ctx, "ai.read_picture", trace.WithAttributes(
+		attribute.Int("image.size", len(req.Image)),
+	))
+	defer func() { tracing.End(span, err) }()
+	if len(req.Language) == 0 {
+		req.Language = DefaultLanguage
 	}
-	fmt.Println("raw response json: ", response)
-
-	var parsed_response VisionResponse
-	err = json.Unmarshal(response.Body(), &parsed_response)
+	prompt, err := GetPrompt(ctx, "read_picture", req.Language)
 	if err != nil {
-		log.Fatalf("Failed to parse response: %v\n", err)
+		return Json{}, err
 	}
-
-	return parsed_response
-}
-
-// TODO not used delete
-func StartVision(image []byte) {
-	var question string = "Read the text in the image. Extract the word being defined and its definition. If possible also a example of how its used." +
-		"Now i want you to respond in the format of json. Key ist the important word and value its definition and example." +
-		"I will turn your answer into a json file so please adhere to the format so that i can parse the file easily." +
-		"Start with { and end with }. No explaination before or after the json." +
-		"And dont format with new line or tabs or spaces. Just the json." +
-		` The json will be marshaled into the following go struct:
-							type Json struct {
-								// The JSON object that will be read from the file
-								Glossary   string
-								Definition string
-								Example    string
-							}`
-	var response VisionResponse = CallVisionApi(question, image)
-	fmt.Printf("%#v\n", response)
+	res, response, err := Extract(ctx, GetProvider(), prompt, req.Image)
+	if response != nil && !response.Cached {
+		if err := RecordUsage(ctx, req.UserID, "read_picture", response.Model, response.Usage); err != nil {
+			slog.ErrorContext(ctx, "failed to record ai usage", "err", err)
+		}
+	}
+	return res, err
 }
 
-func ReadPicture(image []byte) Json {
-	var question string = "Read the text in the image and find the word of interest. The word is either highlighted or stands out. Secondly, if possible extract the context the word is used and use this as inspiration when formulating the example. You will fill out a json with the following information: Glossary: this is the word that stood out the most. Definition: here you write a sentance about the meaning of the word that stood out. Example: here you write like 1-3 senatances with an example where the word is put into a context. Here you could use the same context as in the image but its not needed. Feel free to come up with your own example also so that its crystal clear and a good example of how the word that stood out is often used." +
-		"Now i want you to respond in the format of json." +
-		"I will turn your answer into a json file so please adhere to the format so that i can parse the file easily." +
-		"Start with { and end with }. No text before or after the json." +
-		"And dont format with new line or tabs or spaces. Just the json." +
-		` The json will be marshaled into the following go struct:
-							type Json struct {
-								Glossary   string
-								Definition string
-								Example    string
-							}`
-	var response VisionResponse = CallVisionApi(question, image)
-	// fmt.Printf("%#v\n", response)
-	res := ReadJsonBytes([]byte(response.Choices[0].Message.Content))
-	fmt.Println("\nGerman Word class: ")
-	fmt.Printf("%#v\n %v\n %v\n %v\n", res, res.Definition, res.Example, res.Glossary)
-	return res
+// Extract asks the provider for the word of interest in the image with
+// the read_picture prompt. The response is returned whenever the
+// provider answered, even if the answer couldn't be parsed.
+func Extract(ctx context.Context, provider Provider, prompt Prompt, image []byte) (Json, *VisionResponse, error) {
+	question, err := prompt.Render(ReadPictureData{MaxExampleSentences: 3})
+	if err != nil {
+		return Json{}, nil, err
+	}
+	ctx, span := tracing.Start(ctx, "ai.vision", trace.WithAttributes(
+		attribute.String("ai.provider", provider.Name()),
+		attribute.String("ai.model", modelVision),
+		attribute.String("ai.prompt_version", prompt.ID()),
+	))
+	start := time.Now()
+	response, err := provider.Vision(ctx, VisionRequest{
+		Model:         modelVision,
+		Prompt:        question,
+		PromptVersion: prompt.ID(),
+		Image:         image,
+	})
+	observeVision(provider, modelVision, start, response, err)
+	span.SetAttributes(attribute.Bool("ai.cached", response.Cached))
+	tracing.End(span, err)
+	if err != nil {
+		return Json{}, nil, err
+	}
+	if len(response.Choices) == 0 {
+		return Json{}, &response, errors.New("vision api returned no choices")
+	}
+	var res Json
+	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &res); err != nil {
+		return Json{}, &response, fmt.Errorf("failed to parse extraction: %w", err)
+	}
+	res.PromptVersion = prompt.ID()
+	return res, &response, nil
 }
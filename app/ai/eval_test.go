@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"smartquiz/app/conf"
+)
+
+var record = flag.Bool("record", false, "record the responses of the evaluation with the OpenAI API, needs API_KEY")
+
+const (
+	evalDir              = "testdata/eval"
+	minHeadwordPrecision = 0.8
+)
+
+// evalCase is an image of the golden dataset with the expected
+// extraction, stored as <name>.png and <name>.json in evalDir.
+type evalCase struct {
+	Name  string `json:"-"`
+	Image []byte `json:"-"`
+	// Language of the prompt.
+	Language string `json:"language"`
+	// Glossary lists the accepted headwords.
+	Glossary []string `json:"glossary"`
+	// Definition lists words of which the definition should contain at
+	// least one.
+	Definition []string `json:"definition"`
+}
+
+func loadEvalCases(t *testing.T) []evalCase {
+	images, err := filepath.Glob(filepath.Join(evalDir, "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []evalCase
+	for _, image := range images {
+		name := strings.TrimSuffix(filepath.Base(image), ".png")
+		b, err := os.ReadFile(filepath.Join(evalDir, name+".json"))
+		if err != nil {
+			t.Fatalf("missing expectation of %s: %v", image, err)
+		}
+		c := evalCase{Name: name}
+		if err := json.Unmarshal(b, &c); err != nil {
+			t.Fatalf("invalid expectation of %s: %v", image, err)
+		}
+		if c.Image, err = os.ReadFile(image); err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no evaluation cases in %s", evalDir)
+	}
+	return cases
+}
+
+func (c evalCase) matchesGlossary(glossary string) bool {
+	for _, expected := range c.Glossary {
+		if strings.EqualFold(normalizeHeadword(expected), normalizeHeadword(glossary)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c evalCase) matchesDefinition(definition string) bool {
+	for _, word := range c.Definition {
+		if strings.Contains(strings.ToLower(definition), strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHeadword(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `.,;:!?"'`)
+}
+
+// checkSchema checks that the answer is exactly the JSON object the
+// prompt asks for, with all fields filled in.
+func checkSchema(content string) error {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.DisallowUnknownFields()
+	var res Json
+	if err := dec.Decode(&res); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("text after the JSON object")
+	}
+	for field, value := range map[string]string{"Glossary": res.Glossary, "Definition": res.Definition, "Example": res.Example} {
+		if len(strings.TrimSpace(value)) == 0 {
+			return fmt.Errorf("%s is empty", field)
+		}
+	}
+	return nil
+}
+
+// TestEval runs the golden dataset through the extraction with the
+// recorded responses in testdata/eval/cassettes and reports the
+// precision of the headword and the share of schema valid answers.
+// It's skipped until responses are recorded. After changing a prompt,
+// record new responses with
+//
+//	API_KEY=... go test ./app/ai -run TestEval -record -v
+//
+// Only commit responses recorded this way.
+func TestEval(t *testing.T) {
+	dir := filepath.Join(evalDir, "cassettes")
+	cassette := NewCassette(dir)
+	if *record {
+		if len(conf.Get().AI.APIKey) == 0 {
+			t.Fatal("recording needs the API_KEY of OpenAI")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		cassette.Provider = OpenAI{}
+	} else if recorded, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(recorded) == 0 {
+		t.Skipf("no recorded responses in %s, record them with -record", dir)
+	}
+
+	cases := loadEvalCases(t)
+	report := evaluate(t, cassette, cases)
+	t.Logf("extraction evaluation\n%s", report)
+
+	if report.Valid < report.Cases {
+		t.Errorf("%d of %d answers don't match the schema", report.Cases-report.Valid, report.Cases)
+	}
+	if report.Precision() < minHeadwordPrecision {
+		t.Errorf("headword precision %.2f is below %.2f", report.Precision(), minHeadwordPrecision)
+	}
+}
+
+// evalReport counts the answers of the evaluation.
+type evalReport struct {
+	Cases int
+	// Valid answers match the schema, only they are scored.
+	Valid       int
+	Headwords   int
+	Definitions int
+}
+
+// Precision is the share of valid answers with the expected headword.
+func (r evalReport) Precision() float64 {
+	if r.Valid == 0 {
+		return 0
+	}
+	return float64(r.Headwords) / float64(r.Valid)
+}
+
+func (r evalReport) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "cases:              %d\n", r.Cases)
+	fmt.Fprintf(&b, "schema valid:       %d/%d\n", r.Valid, r.Cases)
+	fmt.Fprintf(&b, "headword precision: %d/%d (%.0f%%)\n", r.Headwords, r.Valid, r.Precision()*100)
+	fmt.Fprintf(&b, "definition matches: %d/%d\n", r.Definitions, r.Valid)
+	return b.String()
+}
+
+// evaluate runs the cases through the extraction with the provider.
+func evaluate(t *testing.T, provider Provider, cases []evalCase) evalReport {
+	t.Helper()
+	report := evalReport{Cases: len(cases)}
+	for _, c := range cases {
+		prompt, err := Prompts().Latest("read_picture", c.Language)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, response, err := Extract(context.Background(), provider, prompt, c.Image)
+		if errors.Is(err, ErrNoCassette) {
+			t.Fatalf("%s: %v, record the responses with -record", c.Name, err)
+		}
+		if response == nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if err == nil {
+			err = checkSchema(response.Choices[0].Message.Content)
+		}
+		if err != nil {
+			t.Logf("%s: invalid answer: %v", c.Name, err)
+			continue
+		}
+		report.Valid++
+		if c.matchesGlossary(res.Glossary) {
+			report.Headwords++
+		} else {
+			t.Logf("%s: expected headword %q, got %q", c.Name, c.Glossary[0], res.Glossary)
+		}
+		if c.matchesDefinition(res.Definition) {
+			report.Definitions++
+		} else {
+			t.Logf("%s: definition %q mentions none of %q", c.Name, res.Definition, c.Definition)
+		}
+	}
+	return report
+}
+
+// scriptedProvider answers with a fixed content per image.
+type scriptedProvider map[string]string
+
+func (p scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p scriptedProvider) Vision(ctx context.Context, req VisionRequest) (VisionResponse, error) {
+	sum := sha256.Sum256(req.Image)
+	content, ok := p[hex.EncodeToString(sum[:])]
+	if !ok {
+		return VisionResponse{}, errors.New("unexpected image")
+	}
+	return VisionResponse{Model: req.Model, Choices: []Choice{{Message: Message{Content: content}}}}, nil
+}
+
+// TestEvalScoring runs the scoring of TestEval on known answers.
+func TestEvalScoring(t *testing.T) {
+	answers := map[string]string{
+		// Headword and definition match.
+		"angelegenheit": `{"Glossary":"die Angelegenheit","Definition":"Eine Sache, um die man sich kümmern muss.","Example":"Das ist meine Angelegenheit."}`,
+		"bravour":       `{"Glossary":"Bravour.","Definition":"Großes Geschick.","Example":"Sie bestand mit Bravour."}`,
+		// Wrong headword.
+		"eloquence": `{"Glossary":"elegance","Definition":"The ability to speak well.","Example":"She spoke with eloquence."}`,
+		// Not the schema, the example is missing.
+		"fensterbrett": `{"Glossary":"Fensterbrett","Definition":"Die Platte unter dem Fenster.","Example":""}`,
+		// The definition mentions none of the expected words.
+		"unanimous": `{"Glossary":"unanimous","Definition":"Without any objection.","Example":"The vote was unanimous."}`,
+	}
+	cases := loadEvalCases(t)
+	provider := scriptedProvider{}
+	for _, c := range cases {
+		answer, ok := answers[c.Name]
+		if !ok {
+			t.Fatalf("no answer for %s", c.Name)
+		}
+		sum := sha256.Sum256(c.Image)
+		provider[hex.EncodeToString(sum[:])] = answer
+	}
+
+	report := evaluate(t, provider, cases)
+	expected := evalReport{Cases: 5, Valid: 4, Headwords: 3, Definitions: 3}
+	if report != expected {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+	if p := report.Precision(); p != 0.75 {
+		t.Errorf("expected a headword precision of 0.75, got %v", p)
+	}
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"gorm.io/gorm"
+)
+
+// maxErrorLength cuts off long provider responses in the error.
+const maxErrorLength = 2000
+
+// ErrNoImage is returned by RetryFailedUpload for uploads recorded before
+// the pictures were kept.
+var ErrNoImage = errors.New("the picture of the upload wasn't kept")
+
+// RecordFailedUpload keeps the failed extraction of an uploaded picture
+// for the admin console, source is web or api. The picture is kept to
+// retry the extraction with RetryFailedUpload, the checksum recognizes
+// it when the user uploads it again.
+func RecordFailedUpload(ctx context.Context, source string, req PictureRequest, extractErr error) {
+	sum := sha256.Sum256(req.Image)
+	record := types.FailedUpload{
+		UserID:      req.UserID,
+		Source:      source,
+		Language:    req.Language,
+		ImageSize:   len(req.Image),
+		ImageSHA256: hex.EncodeToString(sum[:]),
+		Image:       req.Image,
+		Error:       errorMessage(extractErr),
+	}
+	// The request may have been cancelled, which is often why it failed.
+	err := db.Get().WithContext(context.WithoutCancel(ctx)).Create(&record).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record the failed upload", "err", err)
+	}
+}
+
+// RetryFailedUpload runs the extraction of the failed upload again, with
+// the quota of its user. On success the entry is created and the failed
+// upload is deleted, otherwise its error is replaced by the new one.
+func RetryFailedUpload(ctx context.Context, upload types.FailedUpload) (types.GermanWord, error) {
+	if len(upload.Image) == 0 {
+		return types.GermanWord{}, ErrNoImage
+	}
+	if err := CheckQuota(ctx, upload.UserID); err != nil {
+		return types.GermanWord{}, err
+	}
+	res, err := ReadPicture(ctx, PictureRequest{
+		UserID:   upload.UserID,
+		Image:    upload.Image,
+		Language: upload.Language,
+	})
+	if err != nil {
+		update := db.Get().WithContext(ctx).Model(&upload).Update("error", errorMessage(err))
+		if update.Error != nil {
+			slog.ErrorContext(ctx, "failed to update the failed upload", "id", upload.ID, "err", update.Error)
+		}
+		return types.GermanWord{}, err
+	}
+	word := types.GermanWord{
+		UserID:        upload.UserID,
+		Example:       res.Example,
+		GermanWord:    res.Glossary,
+		Definition:    res.Definition,
+		PromptVersion: res.PromptVersion,
+	}
+	err = db.Get().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&word).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&upload).Error
+	})
+	return word, err
+}
+
+func errorMessage(err error) string {
+	msg := err.Error()
+	if len(msg) > maxErrorLength {
+		msg = strings.ToValidUTF8(msg[:maxErrorLength], "")
+	}
+	return msg
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"smartquiz/app/dbtest"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+)
+
+func TestRecordFailedUpload(t *testing.T) {
+	database := dbtest.New(t)
+	user := auth.User{Email: "user@example.com", FirstName: "Uwe", LastName: "Upload"}
+	database.Create(&user)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := PictureRequest{UserID: user.ID, Image: []byte("picture"), Language: "de"}
+	RecordFailedUpload(ctx, "api", req, errors.New("provider error: "+strings.Repeat("x", 3000)))
+
+	var uploads []types.FailedUpload
+	database.Find(&uploads)
+	if len(uploads) != 1 {
+		t.Fatalf("expected the failed upload of a cancelled request to be recorded, got %d", len(uploads))
+	}
+	u := uploads[0]
+	if u.UserID != user.ID || u.Source != "api" || u.ImageSize != 7 || len(u.ImageSHA256) != 64 || string(u.Image) != "picture" || len(u.Error) != maxErrorLength {
+		t.Errorf("unexpected failed upload %+v", u)
+	}
+}
+
+func TestRetryFailedUpload(t *testing.T) {
+	database := dbtest.New(t)
+	user := auth.User{Email: "user@example.com", FirstName: "Uwe", LastName: "Upload"}
+	database.Create(&user)
+	provider := scriptedProvider{}
+	SetProvider(provider)
+	t.Cleanup(func() { SetProvider(nil) })
+	ctx := context.Background()
+
+	legacy := types.FailedUpload{UserID: user.ID, Source: "web", Error: "timeout"}
+	database.Create(&legacy)
+	if _, err := RetryFailedUpload(ctx, legacy); !errors.Is(err, ErrNoImage) {
+		t.Errorf("expected ErrNoImage without a picture, got %v", err)
+	}
+
+	RecordFailedUpload(ctx, "web", PictureRequest{UserID: user.ID, Image: []byte("picture"), Language: "de"}, errors.New("timeout"))
+	var upload types.FailedUpload
+	database.Last(&upload)
+	if _, err := RetryFailedUpload(ctx, upload); err == nil {
+		t.Fatal("expected the retry to fail")
+	}
+	database.First(&upload, upload.ID)
+	if upload.Error == "timeout" {
+		t.Error("expected the error of the retry to be stored")
+	}
+
+	sum := sha256.Sum256(upload.Image)
+	provider[hex.EncodeToString(sum[:])] = `{"Glossary":"das Haus","Definition":"Ein Gebäude.","Example":"Das Haus ist alt."}`
+	word, err := RetryFailedUpload(ctx, upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word.ID == 0 || word.UserID != user.ID || word.GermanWord != "das Haus" {
+		t.Errorf("unexpected entry %+v", word)
+	}
+	var left int64
+	database.Model(&types.FailedUpload{}).Where("id = ?", upload.ID).Count(&left)
+	if left != 0 {
+		t.Error("expected the failed upload to be deleted")
+	}
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	visionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "smartquiz",
+		Subsystem: "ai",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the vision calls by provider, model and whether the response was cached.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"provider", "model", "cached"})
+	visionTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smartquiz",
+		Subsystem: "ai",
+		Name:      "tokens_total",
+		Help:      "Tokens billed by the provider by model and type, prompt or completion.",
+	}, []string{"model", "type"})
+	visionErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smartquiz",
+		Subsystem: "ai",
+		Name:      "errors_total",
+		Help:      "Failed vision calls by provider and model.",
+	}, []string{"provider", "model"})
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smartquiz",
+		Subsystem: "ai",
+		Name:      "cache_requests_total",
+		Help:      "Lookups of the response cache by result, hits, misses or bypassed.",
+	}, []string{"result"})
+)
+
+// observeVision records a call to the provider. Models are labeled with
+// the requested name, the dated names of the responses would create a
+// new series with every model update.
+func observeVision(provider Provider, model string, start time.Time, response VisionResponse, err error) {
+	if err != nil {
+		visionErrors.WithLabelValues(provider.Name(), model).Inc()
+		return
+	}
+	visionDuration.WithLabelValues(provider.Name(), model, strconv.FormatBool(response.Cached)).
+		Observe(time.Since(start).Seconds())
+	if !response.Cached {
+		visionTokens.WithLabelValues(model, "prompt").Add(float64(response.Usage.PromtTokens))
+		visionTokens.WithLabelValues(model, "completion").Add(float64(response.Usage.CompletionTokens))
+	}
+}
+
+// countCache records a cache lookup on /metrics and /debug/vars.
+func countCache(result string) {
+	CacheStats.Add(result, 1)
+	cacheRequests.WithLabelValues(result).Inc()
+}
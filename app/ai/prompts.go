@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+)
+
+// DefaultLanguage is used when a prompt has no variant in the requested
+// language.
+const DefaultLanguage = "en"
+
+// Prompts are text/template files named prompts/<name>/<version>.<language>.tmpl,
+// e.g. prompts/read_picture/v2.de.tmpl. Never change a released version,
+// add a new one instead so cached responses and stored entries can be
+// traced back to the prompt that generated them.
+//
+//go:embed prompts
+var promptFS embed.FS
+
+// Prompt is a versioned prompt template.
+type Prompt struct {
+	Name     string
+	Version  string
+	Language string
+	Text     string
+	tmpl     *template.Template
+}
+
+// ID identifies the prompt, it is stored with every generated entry.
+func (p Prompt) ID() string {
+	return p.Name + "/" + p.Version + "/" + p.Language
+}
+
+// Render executes the template with the given data.
+func (p Prompt) Render(data any) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", p.ID(), err)
+	}
+	return b.String(), nil
+}
+
+// NewPrompt parses the template text of a prompt.
+func NewPrompt(name, version, language, text string) (Prompt, error) {
+	p := Prompt{Name: name, Version: version, Language: language, Text: text}
+	tmpl, err := template.New(p.ID()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return p, fmt.Errorf("invalid prompt %s: %w", p.ID(), err)
+	}
+	p.tmpl = tmpl
+	return p, nil
+}
+
+// Registry holds all versions of the prompts, by name and language.
+type Registry struct {
+	prompts map[string][]Prompt // name/language -> sorted by version
+}
+
+// LoadPrompts reads the prompts from fsys, see promptFS for the layout.
+func LoadPrompts(fsys fs.FS) (*Registry, error) {
+	r := &Registry{prompts: make(map[string][]Prompt)}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if path.Ext(p) != ".tmpl" {
+			return nil
+		}
+		version, language, ok := strings.Cut(strings.TrimSuffix(path.Base(p), ".tmpl"), ".")
+		if !ok || versionNumber(version) < 0 {
+			return fmt.Errorf("invalid prompt file name %s, expected <version>.<language>.tmpl", p)
+		}
+		text, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		prompt, err := NewPrompt(path.Base(path.Dir(p)), version, language, string(text))
+		if err != nil {
+			return err
+		}
+		key := prompt.Name + "/" + prompt.Language
+		r.prompts[key] = append(r.prompts[key], prompt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, versions := range r.prompts {
+		sort.Slice(versions, func(i, j int) bool {
+			return versionNumber(versions[i].Version) < versionNumber(versions[j].Version)
+		})
+	}
+	return r, nil
+}
+
+// Latest returns the newest version of the prompt in the language, or in
+// the DefaultLanguage if there is no such variant.
+func (r *Registry) Latest(name, language string) (Prompt, error) {
+	versions, ok := r.prompts[name+"/"+language]
+	if !ok {
+		versions, ok = r.prompts[name+"/"+DefaultLanguage]
+	}
+	if !ok {
+		return Prompt{}, fmt.Errorf("no prompt named %s", name)
+	}
+	return versions[len(versions)-1], nil
+}
+
+// Version returns a specific version of the prompt.
+func (r *Registry) Version(name, version, language string) (Prompt, error) {
+	for _, p := range r.prompts[name+"/"+language] {
+		if p.Version == version {
+			return p, nil
+		}
+	}
+	return Prompt{}, fmt.Errorf("no prompt %s/%s/%s", name, version, language)
+}
+
+// List returns the latest version of every prompt and language.
+func (r *Registry) List() []Prompt {
+	var list []Prompt
+	for _, versions := range r.prompts {
+		list = append(list, versions[len(versions)-1])
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID() < list[j].ID()
+	})
+	return list
+}
+
+// versionNumber returns n for version "vn" or -1 if it's not a version.
+func versionNumber(version string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || !strings.HasPrefix(version, "v") {
+		return -1
+	}
+	return n
+}
+
+// Prompts returns the registry of the embedded prompts.
+var Prompts = sync.OnceValue(func() *Registry {
+	sub, err := fs.Sub(promptFS, "prompts")
+	if err != nil {
+		panic(err)
+	}
+	r, err := LoadPrompts(sub)
+	if err != nil {
+		panic(err)
+	}
+	return r
+})
+
+// GetPrompt returns the prompt to use for name and language. Admins can
+// override the embedded prompts without a new release, the newest
+// override wins.
+func GetPrompt(ctx context.Context, name, language string) (Prompt, error) {
+	builtin, err := Prompts().Latest(name, language)
+	if err != nil {
+		return builtin, err
+	}
+	var override types.PromptOverride
+	res := db.Get().WithContext(ctx).
+		Where("name = ? AND language = ?", name, builtin.Language).
+		Order("id desc").
+		Limit(1).
+		Find(&override)
+	if res.Error != nil {
+		return builtin, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return builtin, nil
+	}
+	return NewPrompt(name, OverrideVersion(override.ID), builtin.Language, override.Template)
+}
+
+// promptData is example data of every prompt, to check that a new
+// template can be rendered.
+var promptData = map[string]any{
+	"read_picture": ReadPictureData{MaxExampleSentences: 3},
+}
+
+// ValidatePrompt checks that the template text parses and renders with
+// the data the prompt is used with.
+func ValidatePrompt(name, language, text string) error {
+	p, err := NewPrompt(name, "draft", language, text)
+	if err != nil {
+		return err
+	}
+	_, err = p.Render(promptData[name])
+	return err
+}
+
+// OverrideVersion is the version of a prompt override.
+func OverrideVersion(id uint) string {
+	return fmt.Sprintf("override-%d", id)
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadPrompts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"greet/v1.en.tmpl":  {Data: []byte("Hello {{ .Name }}")},
+		"greet/v2.en.tmpl":  {Data: []byte("Hi {{ .Name }}")},
+		"greet/v10.en.tmpl": {Data: []byte("Hey {{ .Name }}")},
+		"greet/v1.de.tmpl":  {Data: []byte("Hallo {{ .Name }}")},
+		"greet/README.md":   {Data: []byte("not a prompt")},
+	}
+	r, err := LoadPrompts(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		language string
+		id       string
+	}{
+		{"en", "greet/v10/en"},
+		{"de", "greet/v1/de"},
+		{"fr", "greet/v10/en"},
+	}
+	for _, test := range tests {
+		p, err := r.Latest("greet", test.language)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ID() != test.id {
+			t.Errorf("latest greet in %s: expected %s, got %s", test.language, test.id, p.ID())
+		}
+	}
+
+	p, err := r.Version("greet", "v2", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := p.Render(map[string]string{"Name": "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hi Anna" {
+		t.Errorf("expected %q, got %q", "Hi Anna", text)
+	}
+
+	if _, err := r.Latest("missing", "en"); err == nil {
+		t.Error("expected an error for a missing prompt")
+	}
+	if n := len(r.List()); n != 2 {
+		t.Errorf("expected 2 prompts in the list, got %d", n)
+	}
+}
+
+func TestLoadPromptsInvalidName(t *testing.T) {
+	fsys := fstest.MapFS{"greet/latest.tmpl": {Data: []byte("Hello")}}
+	if _, err := LoadPrompts(fsys); err == nil {
+		t.Fatal("expected an error for a file name without version and language")
+	}
+}
+
+func TestEmbeddedPrompts(t *testing.T) {
+	for _, p := range Prompts().List() {
+		if _, err := p.Render(promptData[p.Name]); err != nil {
+			t.Errorf("embedded prompt %s: %v", p.ID(), err)
+		}
+	}
+}
+
+func TestValidatePrompt(t *testing.T) {
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"Give {{ .MaxExampleSentences }} examples.", true},
+		{"Give {{ .MaxExampleSentences examples.", false},
+		{"Give {{ .Examples }} examples.", false},
+	}
+	for _, test := range tests {
+		err := ValidatePrompt("read_picture", "en", test.text)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidatePrompt(%q): expected valid %v, got %v", test.text, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"smartquiz/app/conf"
+)
+
+// VisionRequest asks a model about an image.
+type VisionRequest struct {
+	Model  string
+	Prompt string
+	// PromptVersion identifies the prompt for caching.
+	PromptVersion string
+	Image         []byte
+}
+
+// Provider sends requests to an AI model.
+type Provider interface {
+	// Name identifies the provider in cache keys.
+	Name() string
+	Vision(ctx context.Context, req VisionRequest) (VisionResponse, error)
+}
+
+// Checker is implemented by providers that can tell if they are usable
+// without sending a request.
+type Checker interface {
+	Check() error
+}
+
+// CheckProvider returns an error if p is known to be unusable.
+func CheckProvider(p Provider) error {
+	if c, ok := p.(Checker); ok {
+		return c.Check()
+	}
+	return nil
+}
+
+// OpenAI is the provider of the OpenAI chat completions API.
+type OpenAI struct{}
+
+func (OpenAI) Name() string {
+	return "openai"
+}
+
+// Check fails without an API key.
+func (OpenAI) Check() error {
+	if len(conf.Get().AI.APIKey) == 0 {
+		return errors.New("no API key configured")
+	}
+	return nil
+}
+
+func (OpenAI) Vision(ctx context.Context, req VisionRequest) (VisionResponse, error) {
+	return callVisionApi(ctx, req.Model, req.Prompt, req.Image)
+}
+
+var (
+	providerMu sync.Mutex
+	provider   Provider
+)
+
+// GetProvider returns the provider used by the app. By default this is
+// OpenAI with a response cache in front of it.
+func GetProvider() Provider {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	if provider == nil {
+		provider = NewCache(OpenAI{}, cacheTTLFromConfig())
+	}
+	return provider
+}
+
+// SetProvider replaces the provider, for example with a fake one in
+// tests.
+func SetProvider(p Provider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	provider = p
+}
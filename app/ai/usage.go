@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+)
+
+// Price of a model in US dollars per million tokens.
+type Price = conf.Price
+
+// defaultPrices are the OpenAI list prices at the time of writing. They
+// can be overridden with AI_PRICES.
+var defaultPrices = map[string]Price{
+	modelVision: {Prompt: 0.15, Completion: 0.60},
+	modelChat:   {Prompt: 0.50, Completion: 1.50},
+	modelEmbed:  {Prompt: 0.10},
+}
+
+var prices = sync.OnceValue(func() map[string]Price {
+	prices := make(map[string]Price, len(defaultPrices))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	// Invalid prices are reported by conf.Check, the app doesn't start
+	// with them.
+	overrides, err := conf.ParsePrices(conf.Get().AI.Prices)
+	if err != nil {
+		slog.Error("invalid AI_PRICES, using the default prices", "err", err)
+		return prices
+	}
+	for model, price := range overrides {
+		prices[model] = price
+	}
+	return prices
+})
+
+// Cost returns the cost of the usage in US dollars. The provider reports
+// dated model names like gpt-4o-mini-2024-07-18, so the price of the
+// longest matching model prefix is used.
+func Cost(prices map[string]Price, model string, usage Usage) float64 {
+	price, match := Price{}, ""
+	for name, p := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(match) {
+			price, match = p, name
+		}
+	}
+	if len(match) == 0 {
+		slog.Warn("no price configured for model", "model", model)
+	}
+	return (float64(usage.PromtTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+// RecordUsage stores the usage of a model call made for the user.
+func RecordUsage(ctx context.Context, userID uint, operation string, model string, usage Usage) error {
+	record := types.AIUsage{
+		UserID:           userID,
+		Operation:        operation,
+		Model:            model,
+		PromptTokens:     usage.PromtTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             Cost(prices(), model, usage),
+	}
+	return db.Get().WithContext(ctx).Create(&record).Error
+}
+
+// UsageSummary sums up the recorded usage of a user.
+type UsageSummary struct {
+	Requests int64
+	Tokens   int64
+	Cost     float64
+}
+
+// UsageSince sums up the usage of the user since the given time.
+func UsageSince(ctx context.Context, userID uint, since time.Time) (UsageSummary, error) {
+	var summary UsageSummary
+	err := db.Get().WithContext(ctx).Model(&types.AIUsage{}).
+		Select("count(*) AS requests, coalesce(sum(total_tokens), 0) AS tokens, coalesce(sum(cost), 0) AS cost").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&summary).Error
+	return summary, err
+}
+
+// Quota limits the tokens a user may use per day and month. Zero means
+// unlimited.
+type Quota struct {
+	Daily   int64
+	Monthly int64
+}
+
+func QuotaFromConfig() Quota {
+	return Quota{
+		Daily:   conf.Get().AI.DailyTokenQuota,
+		Monthly: conf.Get().AI.MonthlyTokenQuota,
+	}
+}
+
+// QuotaError is returned by CheckQuota when the user used up a quota.
+type QuotaError struct {
+	Period string
+	Limit  int64
+	Used   int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("the %s AI quota of %d tokens is used up", e.Period, e.Limit)
+}
+
+// ErrAnonymous is returned by CheckQuota for calls without a user. All
+// anonymous visitors would share one quota and nothing would limit a
+// single one of them.
+var ErrAnonymous = errors.New("ai: calls to the provider need a logged in user")
+
+// CheckQuota returns a *QuotaError if the user may not make another
+// model call. It must be called before calling the provider.
+func CheckQuota(ctx context.Context, userID uint) error {
+	if userID == 0 {
+		return ErrAnonymous
+	}
+	quota := QuotaFromConfig()
+	now := time.Now()
+	periods := []struct {
+		name  string
+		limit int64
+		since time.Time
+	}{
+		{"daily", quota.Daily, StartOfDay(now)},
+		{"monthly", quota.Monthly, StartOfMonth(now)},
+	}
+	for _, p := range periods {
+		if p.limit == 0 {
+			continue
+		}
+		usage, err := UsageSince(ctx, userID, p.since)
+		if err != nil {
+			return err
+		}
+		if usage.Tokens >= p.limit {
+			return &QuotaError{Period: p.name, Limit: p.limit, Used: usage.Tokens}
+		}
+	}
+	return nil
+}
+
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
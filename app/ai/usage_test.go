@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCost(t *testing.T) {
+	prices := map[string]Price{
+		"gpt-4o":      {Prompt: 2.50, Completion: 10},
+		"gpt-4o-mini": {Prompt: 0.15, Completion: 0.60},
+	}
+	usage := Usage{PromtTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+
+	// The dated model name must match the longest prefix, gpt-4o-mini.
+	cost := Cost(prices, "gpt-4o-mini-2024-07-18", usage)
+	if want := 0.00045; math.Abs(cost-want) > 1e-12 {
+		t.Fatalf("expected cost %v, got %v", want, cost)
+	}
+	if cost := Cost(prices, "unknown-model", usage); cost != 0 {
+		t.Fatalf("expected unknown models to cost 0, got %v", cost)
+	}
+}
+
+func TestCheckQuotaAnonymous(t *testing.T) {
+	if err := CheckQuota(context.Background(), 0); !errors.Is(err, ErrAnonymous) {
+		t.Fatalf("expected anonymous calls to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// Envelope is the body of every successful API response.
+type Envelope struct {
+	Data any   `json:"data"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// Meta holds the pagination information of list responses.
+type Meta struct {
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+	Total   int64 `json:"total"`
+}
+
+// ErrorEnvelope is the body of every failed API response.
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Error is an error that will be rendered as an ErrorEnvelope with
+// the given status code. Any other error returned from a handler is
+// reported as an internal server error.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  map[string]string
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+func errBadRequest(msg string) Error {
+	return Error{Status: http.StatusBadRequest, Code: "bad_request", Message: msg}
+}
+
+func errValidation(fields map[string]string) Error {
+	return Error{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    "validation_failed",
+		Message: "one or more fields are invalid",
+		Fields:  fields,
+	}
+}
+
+func errNotFound(msg string) Error {
+	return Error{Status: http.StatusNotFound, Code: "not_found", Message: msg}
+}
+
+var errUnauthorized = Error{
+	Status:  http.StatusUnauthorized,
+	Code:    "unauthorized",
+	Message: "authentication required",
+}
+
+// handler adapts an API handler to a http.HandlerFunc, rendering any
+// returned error as JSON instead of going through the HTML error handler.
+func handler(h kit.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kit := &kit.Kit{
+			Response: w,
+			Request:  r,
+		}
+		if err := h(kit); err != nil {
+			writeError(kit, err)
+		}
+	}
+}
+
+func writeJSON(kit *kit.Kit, status int, v any) error {
+	kit.Response.Header().Set("Content-Type", "application/json")
+	kit.Response.WriteHeader(status)
+	return json.NewEncoder(kit.Response).Encode(v)
+}
+
+func writeData(kit *kit.Kit, status int, data any) error {
+	return writeJSON(kit, status, Envelope{Data: data})
+}
+
+func writeList(kit *kit.Kit, data any, meta Meta) error {
+	return writeJSON(kit, http.StatusOK, Envelope{Data: data, Meta: &meta})
+}
+
+func writeError(kit *kit.Kit, err error) {
+	var apiErr Error
+	if !errors.As(err, &apiErr) {
+		slog.ErrorContext(kit.Request.Context(), "api internal server error", "err", err, "path", kit.Request.URL.Path)
+		apiErr = Error{
+			Status:  http.StatusInternalServerError,
+			Code:    "internal_error",
+			Message: "an unexpected error occurred",
+		}
+	}
+	writeJSON(kit, apiErr.Status, ErrorEnvelope{
+		Error: ErrorBody{
+			Code:    apiErr.Code,
+			Message: apiErr.Message,
+			Fields:  apiErr.Fields,
+		},
+	})
+}
+
+func decodeBody(kit *kit.Kit, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(kit.Response, kit.Request.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errBadRequest("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+// pagination reads the page and per_page query parameters.
+func pagination(kit *kit.Kit) (Meta, error) {
+	meta := Meta{Page: 1, PerPage: defaultPerPage}
+	query := kit.Request.URL.Query()
+	if s := query.Get("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return meta, errBadRequest("page must be a positive integer")
+		}
+		meta.Page = page
+	}
+	if s := query.Get("per_page"); s != "" {
+		perPage, err := strconv.Atoi(s)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return meta, errBadRequest("per_page must be between 1 and 100")
+		}
+		meta.PerPage = perPage
+	}
+	return meta, nil
+}
+
+func (m Meta) offset() int {
+	return (m.Page - 1) * m.PerPage
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+	"smartquiz/app/types"
+	"smartquiz/pkg/ratelimit"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+const testSecret = "a-secret-that-is-long-enough-for-superkit"
+
+// newServer returns the API routes on a test database.
+func newServer(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	database := dbtest.New(t)
+	setupSessions(t)
+	router := chi.NewRouter()
+	InitializeRoutes(router, ratelimit.New(10, time.Hour))
+	return database, router
+}
+
+// setupSessions initializes the cookie store of kit, kit.Setup reads
+// the secret from the .env file in the working directory.
+func setupSessions(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	env := []byte("SUPERKIT_SECRET=" + testSecret + "\n")
+	if err := os.WriteFile(filepath.Join(dir, ".env"), env, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SUPERKIT_SECRET", testSecret)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	kit.Setup()
+}
+
+func createUser(t *testing.T, database *gorm.DB, email string) auth.User {
+	t.Helper()
+	user := auth.User{Email: email, PasswordHash: "x"}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// createToken creates an API token for the user and returns it in
+// plain text.
+func createToken(t *testing.T, database *gorm.DB, user auth.User, scopes ...string) string {
+	t.Helper()
+	plain := fmt.Sprintf("sq_%d_%s", user.ID, strings.Join(scopes, "_"))
+	sum := sha256.Sum256([]byte(plain))
+	token := auth.APIToken{
+		UserID:    user.ID,
+		Name:      "test",
+		TokenHash: hex.EncodeToString(sum[:]),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if err := database.Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func newRequest(t *testing.T, method, path, token string, body any) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, "/api/v1"+path, &buf)
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	return v
+}
+
+func TestAuthentication(t *testing.T) {
+	database, server := newServer(t)
+	user := createUser(t, database, "alice@example.com")
+	read := createToken(t, database, user, auth.ScopeRead)
+	write := createToken(t, database, user, auth.ScopeWrite)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		code   string
+	}{
+		{"no token", http.MethodGet, "/entries", "", http.StatusUnauthorized, "unauthorized"},
+		{"unknown token", http.MethodGet, "/entries", "sq_unknown", http.StatusUnauthorized, "unauthorized"},
+		{"read scope", http.MethodGet, "/entries", read, http.StatusOK, ""},
+		{"write without read scope", http.MethodGet, "/entries", write, http.StatusForbidden, "insufficient_scope"},
+		{"write with read scope", http.MethodPost, "/decks", read, http.StatusForbidden, "insufficient_scope"},
+		{"upload with write scope", http.MethodPost, "/uploads", write, http.StatusForbidden, "insufficient_scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, newRequest(t, tt.method, tt.path, tt.token, nil))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code == "" {
+				return
+			}
+			if res := decode[ErrorEnvelope](t, w); res.Error.Code != tt.code {
+				t.Errorf("got error code %q, want %q", res.Error.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestOtherUsersResources(t *testing.T) {
+	database, server := newServer(t)
+	owner := createUser(t, database, "owner@example.com")
+	other := createUser(t, database, "other@example.com")
+	token := createToken(t, database, other, auth.ScopeRead, auth.ScopeWrite)
+
+	deck := types.Deck{UserID: owner.ID, Name: "Verbs"}
+	if err := database.Create(&deck).Error; err != nil {
+		t.Fatal(err)
+	}
+	word := types.GermanWord{UserID: owner.ID, GermanWord: "gehen", DeckID: &deck.ID}
+	if err := database.Create(&word).Error; err != nil {
+		t.Fatal(err)
+	}
+	entry := fmt.Sprintf("/entries/%d", word.ID)
+	deckPath := fmt.Sprintf("/decks/%d", deck.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{"show entry", http.MethodGet, entry, nil},
+		{"update entry", http.MethodPatch, entry, map[string]string{"word": "laufen"}},
+		{"delete entry", http.MethodDelete, entry, nil},
+		{"show deck", http.MethodGet, deckPath, nil},
+		{"update deck", http.MethodPatch, deckPath, map[string]string{"name": "Mine"}},
+		{"delete deck", http.MethodDelete, deckPath, nil},
+		{"review entry", http.MethodPost, "/reviews", CreateReviewRequest{EntryID: word.ID, Grade: "good"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, newRequest(t, tt.method, tt.path, token, tt.body))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("got status %d, want 404: %s", w.Code, w.Body)
+			}
+			if res := decode[ErrorEnvelope](t, w); res.Error.Code != "not_found" {
+				t.Errorf("got error code %q, want not_found", res.Error.Code)
+			}
+		})
+	}
+
+	var got types.GermanWord
+	if err := database.First(&got, word.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.GermanWord != "gehen" || got.DueAt != nil {
+		t.Errorf("the entry of the owner was changed: %+v", got)
+	}
+	var count int64
+	database.Model(&types.Deck{}).Where("id = ? AND name = ?", deck.ID, "Verbs").Count(&count)
+	if count != 1 {
+		t.Error("the deck of the owner was changed")
+	}
+
+	// Entries can't be moved to the deck of another user either.
+	w := serve(server, newRequest(t, http.MethodPost, "/entries", token, CreateEntryRequest{Word: "laufen", DeckID: &deck.ID}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d for a deck of another user, want 422", w.Code)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	database, server := newServer(t)
+	user := createUser(t, database, "alice@example.com")
+	token := createToken(t, database, user, auth.ScopeRead)
+	for i := 0; i < 5; i++ {
+		word := types.GermanWord{UserID: user.ID, GermanWord: fmt.Sprintf("wort%d", i)}
+		if err := database.Create(&word).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Entries of other users aren't counted.
+	other := createUser(t, database, "other@example.com")
+	database.Create(&types.GermanWord{UserID: other.ID, GermanWord: "fremd"})
+
+	tests := []struct {
+		query   string
+		meta    Meta
+		entries int
+	}{
+		{"", Meta{Page: 1, PerPage: defaultPerPage, Total: 5}, 5},
+		{"?per_page=2", Meta{Page: 1, PerPage: 2, Total: 5}, 2},
+		{"?page=3&per_page=2", Meta{Page: 3, PerPage: 2, Total: 5}, 1},
+		{"?page=4&per_page=2", Meta{Page: 4, PerPage: 2, Total: 5}, 0},
+		{"?per_page=100", Meta{Page: 1, PerPage: 100, Total: 5}, 5},
+	}
+	for _, tt := range tests {
+		t.Run("entries"+tt.query, func(t *testing.T) {
+			w := serve(server, newRequest(t, http.MethodGet, "/entries"+tt.query, token, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", w.Code, w.Body)
+			}
+			res := decode[struct {
+				Data []Entry
+				Meta *Meta
+			}](t, w)
+			if res.Meta == nil || *res.Meta != tt.meta {
+				t.Errorf("got meta %+v, want %+v", res.Meta, tt.meta)
+			}
+			if len(res.Data) != tt.entries {
+				t.Errorf("got %d entries, want %d", len(res.Data), tt.entries)
+			}
+		})
+	}
+
+	for _, query := range []string{"?page=0", "?page=x", "?per_page=0", "?per_page=101"} {
+		t.Run("entries"+query, func(t *testing.T) {
+			w := serve(server, newRequest(t, http.MethodGet, "/entries"+query, token, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("got status %d, want 400", w.Code)
+			}
+			if res := decode[ErrorEnvelope](t, w); res.Error.Code != "bad_request" {
+				t.Errorf("got error code %q, want bad_request", res.Error.Code)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	database, server := newServer(t)
+	user := createUser(t, database, "alice@example.com")
+	token := createToken(t, database, user, auth.ScopeWrite)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		fields map[string]any
+	}{
+		{"entry", "/entries", CreateEntryRequest{Word: " "}, map[string]any{"word": "is required"}},
+		{"deck", "/decks", CreateDeckRequest{}, map[string]any{"name": "is required"}},
+		{"review", "/reviews", CreateReviewRequest{Grade: "perfect"}, map[string]any{
+			"entry_id": "is required",
+			"grade":    "must be one of again, hard, good or easy",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, newRequest(t, http.MethodPost, tt.path, token, tt.body))
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d, want 422: %s", w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("got content type %q", ct)
+			}
+			// The raw JSON, so renamed or additional keys are noticed.
+			want := map[string]any{
+				"error": map[string]any{
+					"code":    "validation_failed",
+					"message": "one or more fields are invalid",
+					"fields":  tt.fields,
+				},
+			}
+			if got := decode[map[string]any](t, w); !reflect.DeepEqual(got, want) {
+				t.Errorf("got body %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCookieSession(t *testing.T) {
+	database, server := newServer(t)
+	user := createUser(t, database, "alice@example.com")
+	if err := database.Create(&types.GermanWord{UserID: user.ID, GermanWord: "gehen"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	sessions := []auth.Session{
+		{UserID: user.ID, Token: "valid", ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: user.ID, Token: "expired", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	for i := range sessions {
+		if err := database.Create(&sessions[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cookie := func(token string) *http.Cookie {
+		w := httptest.NewRecorder()
+		k := &kit.Kit{Response: w, Request: httptest.NewRequest(http.MethodGet, "/", nil)}
+		sess := k.GetSession("user-session")
+		sess.Values["sessionToken"] = token
+		if err := sess.Save(k.Request, w); err != nil {
+			t.Fatal(err)
+		}
+		return w.Result().Cookies()[0]
+	}
+
+	r := newRequest(t, http.MethodGet, "/entries", "", nil)
+	r.AddCookie(cookie("valid"))
+	w := serve(server, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	res := decode[struct{ Data []Entry }](t, w)
+	if len(res.Data) != 1 || res.Data[0].Word != "gehen" {
+		t.Errorf("got entries %+v", res.Data)
+	}
+
+	// Sessions aren't limited to scopes.
+	r = newRequest(t, http.MethodPost, "/decks", "", CreateDeckRequest{Name: "Verbs"})
+	r.AddCookie(cookie("valid"))
+	if w := serve(server, r); w.Code != http.StatusCreated {
+		t.Errorf("got status %d creating a deck, want 201: %s", w.Code, w.Body)
+	}
+
+	r = newRequest(t, http.MethodGet, "/entries", "", nil)
+	r.AddCookie(cookie("expired"))
+	if w := serve(server, r); w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d for an expired session, want 401", w.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// Deck is the API representation of a types.Deck.
+type Deck struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateDeckRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UpdateDeckRequest only updates the fields that are present.
+type UpdateDeckRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+func newDeck(deck types.Deck) Deck {
+	return Deck{
+		ID:          deck.ID,
+		Name:        deck.Name,
+		Description: deck.Description,
+		CreatedAt:   deck.CreatedAt,
+		UpdatedAt:   deck.UpdatedAt,
+	}
+}
+
+func HandleDeckList(kit *kit.Kit) error {
+	meta, err := pagination(kit)
+	if err != nil {
+		return err
+	}
+	query := db.Get().WithContext(kit.Request.Context()).Model(&types.Deck{}).Where("user_id = ?", userID(kit))
+	if err := query.Count(&meta.Total).Error; err != nil {
+		return err
+	}
+	var decks []types.Deck
+	err = query.Order("name, id").Limit(meta.PerPage).Offset(meta.offset()).Find(&decks).Error
+	if err != nil {
+		return err
+	}
+	res := make([]Deck, len(decks))
+	for i, deck := range decks {
+		res[i] = newDeck(deck)
+	}
+	return writeList(kit, res, meta)
+}
+
+func HandleDeckShow(kit *kit.Kit) error {
+	deck, err := findDeck(kit)
+	if err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusOK, newDeck(deck))
+}
+
+func HandleDeckCreate(kit *kit.Kit) error {
+	var req CreateDeckRequest
+	if err := decodeBody(kit, &req); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return errValidation(map[string]string{"name": "is required"})
+	}
+	deck := types.Deck{
+		UserID:      userID(kit),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&deck).Error; err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusCreated, newDeck(deck))
+}
+
+func HandleDeckUpdate(kit *kit.Kit) error {
+	deck, err := findDeck(kit)
+	if err != nil {
+		return err
+	}
+	var req UpdateDeckRequest
+	if err := decodeBody(kit, &req); err != nil {
+		return err
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return errValidation(map[string]string{"name": "must not be empty"})
+		}
+		deck.Name = *req.Name
+	}
+	if req.Description != nil {
+		deck.Description = *req.Description
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Save(&deck).Error; err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusOK, newDeck(deck))
+}
+
+// HandleDeckDelete deletes the deck, its entries are kept without a
+// deck.
+func HandleDeckDelete(kit *kit.Kit) error {
+	deck, err := findDeck(kit)
+	if err != nil {
+		return err
+	}
+	err = db.Get().WithContext(kit.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.GermanWord{}).Where("deck_id = ?", deck.ID).Update("deck_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&deck).Error
+	})
+	if err != nil {
+		return err
+	}
+	kit.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// findDeck loads the deck from the {id} URL parameter. Decks of other
+// users are reported as not found.
+func findDeck(kit *kit.Kit) (types.Deck, error) {
+	id, err := strconv.Atoi(chi.URLParam(kit.Request, "id"))
+	if err != nil {
+		return types.Deck{}, errBadRequest("invalid deck id")
+	}
+	return ownDeck(kit, uint(id))
+}
+
+func ownDeck(kit *kit.Kit, id uint) (types.Deck, error) {
+	var deck types.Deck
+	err := db.Get().WithContext(kit.Request.Context()).First(&deck, "id = ? AND user_id = ?", id, userID(kit)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return deck, errNotFound("deck not found")
+	}
+	return deck, err
+}
+
+// deckParam reads the optional deck_id query parameter, zero if it's
+// missing.
+func deckParam(kit *kit.Kit) (uint, error) {
+	s := kit.Request.URL.Query().Get("deck_id")
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errBadRequest("deck_id must be a positive integer")
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// Entry is the API representation of a types.GermanWord. DueAt is when
+// the entry should be reviewed next, null for entries that were never
+// reviewed.
+type Entry struct {
+	ID            uint       `json:"id"`
+	Word          string     `json:"word"`
+	Definition    string     `json:"definition"`
+	Example       string     `json:"example"`
+	PromptVersion string     `json:"prompt_version,omitempty"`
+	DeckID        *uint      `json:"deck_id"`
+	DueAt         *time.Time `json:"due_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type CreateEntryRequest struct {
+	Word       string `json:"word"`
+	Definition string `json:"definition"`
+	Example    string `json:"example"`
+	DeckID     *uint  `json:"deck_id"`
+}
+
+// UpdateEntryRequest only updates the fields that are present. A
+// deck_id of 0 removes the entry from its deck.
+type UpdateEntryRequest struct {
+	Word       *string `json:"word"`
+	Definition *string `json:"definition"`
+	Example    *string `json:"example"`
+	DeckID     *uint   `json:"deck_id"`
+}
+
+func newEntry(word types.GermanWord) Entry {
+	return Entry{
+		ID:            word.ID,
+		Word:          word.GermanWord,
+		Definition:    word.Definition,
+		Example:       word.Example,
+		PromptVersion: word.PromptVersion,
+		DeckID:        word.DeckID,
+		DueAt:         word.DueAt,
+		CreatedAt:     word.CreatedAt,
+		UpdatedAt:     word.UpdatedAt,
+	}
+}
+
+func HandleEntryList(kit *kit.Kit) error {
+	meta, err := pagination(kit)
+	if err != nil {
+		return err
+	}
+	deckID, err := deckParam(kit)
+	if err != nil {
+		return err
+	}
+	query := db.Get().WithContext(kit.Request.Context()).Model(&types.GermanWord{}).Where("user_id = ?", userID(kit))
+	if deckID != 0 {
+		query = query.Where("deck_id = ?", deckID)
+	}
+	if q := strings.TrimSpace(kit.Request.URL.Query().Get("q")); q != "" {
+		query = query.Where("lower(german_word) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	if err := query.Count(&meta.Total).Error; err != nil {
+		return err
+	}
+	var words []types.GermanWord
+	err = query.Order("id desc").Limit(meta.PerPage).Offset(meta.offset()).Find(&words).Error
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(words))
+	for i, word := range words {
+		entries[i] = newEntry(word)
+	}
+	return writeList(kit, entries, meta)
+}
+
+func HandleEntryShow(kit *kit.Kit) error {
+	word, err := findEntry(kit)
+	if err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusOK, newEntry(word))
+}
+
+func HandleEntryCreate(kit *kit.Kit) error {
+	var req CreateEntryRequest
+	if err := decodeBody(kit, &req); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Word) == "" {
+		return errValidation(map[string]string{"word": "is required"})
+	}
+	if req.DeckID != nil {
+		if err := checkDeck(kit, *req.DeckID); err != nil {
+			return err
+		}
+	}
+	word := types.GermanWord{
+		UserID:     userID(kit),
+		GermanWord: req.Word,
+		Definition: req.Definition,
+		Example:    req.Example,
+		DeckID:     req.DeckID,
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&word).Error; err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusCreated, newEntry(word))
+}
+
+func HandleEntryUpdate(kit *kit.Kit) error {
+	word, err := findEntry(kit)
+	if err != nil {
+		return err
+	}
+	var req UpdateEntryRequest
+	if err := decodeBody(kit, &req); err != nil {
+		return err
+	}
+	if req.Word != nil {
+		if strings.TrimSpace(*req.Word) == "" {
+			return errValidation(map[string]string{"word": "must not be empty"})
+		}
+		word.GermanWord = *req.Word
+	}
+	if req.Definition != nil {
+		word.Definition = *req.Definition
+	}
+	if req.Example != nil {
+		word.Example = *req.Example
+	}
+	if req.DeckID != nil {
+		word.DeckID = nil
+		if *req.DeckID != 0 {
+			if err := checkDeck(kit, *req.DeckID); err != nil {
+				return err
+			}
+			word.DeckID = req.DeckID
+		}
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Save(&word).Error; err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusOK, newEntry(word))
+}
+
+func HandleEntryDelete(kit *kit.Kit) error {
+	word, err := findEntry(kit)
+	if err != nil {
+		return err
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Delete(&word).Error; err != nil {
+		return err
+	}
+	kit.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// findEntry loads the entry from the {id} URL parameter. Entries of
+// other users are reported as not found.
+func findEntry(kit *kit.Kit) (types.GermanWord, error) {
+	var word types.GermanWord
+	id, err := strconv.Atoi(chi.URLParam(kit.Request, "id"))
+	if err != nil {
+		return word, errBadRequest("invalid entry id")
+	}
+	err = db.Get().WithContext(kit.Request.Context()).First(&word, "id = ? AND user_id = ?", id, userID(kit)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return word, errNotFound("entry not found")
+	}
+	return word, err
+}
+
+// checkDeck reports a deck_id that isn't a deck of the current user as
+// invalid.
+func checkDeck(kit *kit.Kit, id uint) error {
+	_, err := ownDeck(kit, id)
+	var apiErr Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return errValidation(map[string]string{"deck_id": "no such deck"})
+	}
+	return err
+}
+
+func userID(kit *kit.Kit) uint {
+	return kit.Auth().(auth.Auth).UserID
+}
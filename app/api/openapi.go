@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+var pathParamRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+func HandleOpenAPI(kit *kit.Kit) error {
+	return writeJSON(kit, http.StatusOK, OpenAPI())
+}
+
+// OpenAPI generates the OpenAPI 3 document from the endpoints table.
+func OpenAPI() map[string]any {
+	schemas := map[string]any{
+		"Meta":          schemaOf(reflect.TypeOf(Meta{}), nil),
+		"ErrorEnvelope": schemaOf(reflect.TypeOf(ErrorEnvelope{}), nil),
+	}
+	paths := map[string]any{}
+	for _, e := range endpoints {
+		path := "/api/v1" + e.Path
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(e.Method)] = operation(e, schemas)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "smartquiz API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "personal API token created on the profile page",
+				},
+				"cookieAuth": map[string]any{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": "user-session",
+				},
+			},
+		},
+		"security": []any{
+			map[string]any{"bearerAuth": []any{}},
+			map[string]any{"cookieAuth": []any{}},
+		},
+	}
+}
+
+func operation(e Endpoint, schemas map[string]any) map[string]any {
+	var params []any
+	for _, match := range pathParamRegex.FindAllStringSubmatch(e.Path, -1) {
+		params = append(params, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "integer"},
+		})
+	}
+	for _, p := range e.Query {
+		params = append(params, map[string]any{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      map[string]any{"type": p.Type},
+		})
+	}
+
+	op := map[string]any{
+		"summary":     e.Summary,
+		"description": "Requires the " + e.Scope + " scope when called with an API token.",
+		"responses":   responses(e, schemas),
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if e.Body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": schemaOf(reflect.TypeOf(e.Body), schemas),
+				},
+			},
+		}
+	}
+	if e.Multipart {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{
+					"schema": map[string]any{
+						"type":     "object",
+						"required": []string{"file"},
+						"properties": map[string]any{
+							"file": map[string]any{"type": "string", "format": "binary"},
+							"language": map[string]any{
+								"type":        "string",
+								"description": "language of the definition and example, e.g. en or de",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	return op
+}
+
+func responses(e Endpoint, schemas map[string]any) map[string]any {
+	errorResponse := map[string]any{
+		"description": "error",
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/ErrorEnvelope"},
+			},
+		},
+	}
+	res := map[string]any{"default": errorResponse}
+	if e.RateLimited {
+		res["429"] = map[string]any{
+			"description": "upload rate limit (rate_limited) or AI quota (quota_exceeded) exceeded",
+			"content":     errorResponse["content"],
+		}
+	}
+	if e.Response == nil {
+		res[strconv.Itoa(e.Status)] = map[string]any{"description": http.StatusText(e.Status)}
+		return res
+	}
+
+	data := schemaOf(reflect.TypeOf(e.Response), schemas)
+	properties := map[string]any{"data": data}
+	if e.List {
+		properties["data"] = map[string]any{"type": "array", "items": data}
+		properties["meta"] = map[string]any{"$ref": "#/components/schemas/Meta"}
+	}
+	res[strconv.Itoa(e.Status)] = map[string]any{
+		"description": http.StatusText(e.Status),
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type":       "object",
+					"properties": properties,
+				},
+			},
+		},
+	}
+	return res
+}
+
+// schemaOf returns the JSON schema of t. Named structs are added to
+// schemas and referenced, unless schemas is nil.
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct:
+		if schemas != nil {
+			if _, ok := schemas[t.Name()]; !ok {
+				schemas[t.Name()] = schemaOf(t, nil)
+			}
+			return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = schemaOf(field.Type, schemas)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	}
+	return map[string]any{}
+}
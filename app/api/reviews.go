@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"smartquiz/app/review"
+	"smartquiz/app/types"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+// Review is the API representation of a types.Review, with the time the
+// entry is due again.
+type Review struct {
+	ID           uint      `json:"id"`
+	EntryID      uint      `json:"entry_id"`
+	Grade        string    `json:"grade"`
+	IntervalDays int       `json:"interval_days"`
+	Ease         float64   `json:"ease"`
+	ReviewedAt   time.Time `json:"reviewed_at"`
+	DueAt        time.Time `json:"due_at"`
+}
+
+// CreateReviewRequest is the answer to an entry, the grade is one of
+// again, hard, good or easy.
+type CreateReviewRequest struct {
+	EntryID uint   `json:"entry_id"`
+	Grade   string `json:"grade"`
+}
+
+// HandleReviewCreate records the answer to an entry and schedules its
+// next review.
+func HandleReviewCreate(kit *kit.Kit) error {
+	var req CreateReviewRequest
+	if err := decodeBody(kit, &req); err != nil {
+		return err
+	}
+	fields := map[string]string{}
+	if req.EntryID == 0 {
+		fields["entry_id"] = "is required"
+	}
+	grade, err := review.ParseGrade(req.Grade)
+	if err != nil {
+		fields["grade"] = "must be one of again, hard, good or easy"
+	}
+	if len(fields) > 0 {
+		return errValidation(fields)
+	}
+	word, rev, err := review.Submit(kit.Request.Context(), userID(kit), req.EntryID, grade, time.Now())
+	if errors.Is(err, review.ErrNotFound) {
+		return errNotFound("entry not found")
+	}
+	if err != nil {
+		return err
+	}
+	return writeData(kit, http.StatusCreated, newReview(rev, word))
+}
+
+// HandleReviewDue lists the entries that are due for review, the most
+// overdue first and the entries that were never reviewed last.
+func HandleReviewDue(kit *kit.Kit) error {
+	meta, err := pagination(kit)
+	if err != nil {
+		return err
+	}
+	deckID, err := deckParam(kit)
+	if err != nil {
+		return err
+	}
+	query := review.Due(kit.Request.Context(), userID(kit), deckID, time.Now())
+	if err := query.Count(&meta.Total).Error; err != nil {
+		return err
+	}
+	var words []types.GermanWord
+	err = query.Order(review.Order).Limit(meta.PerPage).Offset(meta.offset()).Find(&words).Error
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(words))
+	for i, word := range words {
+		entries[i] = newEntry(word)
+	}
+	return writeList(kit, entries, meta)
+}
+
+func newReview(rev types.Review, word types.GermanWord) Review {
+	return Review{
+		ID:           rev.ID,
+		EntryID:      rev.EntryID,
+		Grade:        rev.Grade,
+		IntervalDays: rev.IntervalDays,
+		Ease:         rev.Ease,
+		ReviewedAt:   rev.ReviewedAt,
+		DueAt:        *word.DueAt,
+	}
+}
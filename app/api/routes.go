@@ -0,0 +1,259 @@
+package api
+
+import (
+	"net/http"
+
+	"smartquiz/pkg/ratelimit"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+)
+
+// Param describes a query parameter of an endpoint.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Endpoint describes a single API operation. The endpoints table is
+// used both to mount the routes and to generate the OpenAPI document,
+// so the two can not drift apart.
+type Endpoint struct {
+	Method  string
+	Path    string
+	Summary string
+	// Scope is the API token scope required to call the endpoint.
+	Scope string
+	Query []Param
+	// Body is the JSON request body, nil when the endpoint has none.
+	Body any
+	// Multipart is set for endpoints expecting a "file" form field.
+	Multipart bool
+	// Response is the type inside the "data" field of the envelope,
+	// nil for endpoints responding with 204 No Content.
+	Response any
+	Status   int
+	List     bool
+	// RateLimited endpoints share the upload rate limit.
+	RateLimited bool
+	Handler     kit.HandlerFunc
+}
+
+var paginationParams = []Param{
+	{Name: "page", Type: "integer", Description: "page number, starting at 1"},
+	{Name: "per_page", Type: "integer", Description: "items per page, at most 100"},
+}
+
+var deckParamDoc = Param{Name: "deck_id", Type: "integer", Description: "only entries of this deck"}
+
+var endpoints = []Endpoint{
+	{
+		Method:  http.MethodGet,
+		Path:    "/entries",
+		Summary: "List the entries of the current user",
+		Scope:   auth.ScopeRead,
+		Query: []Param{
+			paginationParams[0],
+			paginationParams[1],
+			{Name: "q", Type: "string", Description: "filter by word"},
+			deckParamDoc,
+		},
+		Response: Entry{},
+		Status:   http.StatusOK,
+		List:     true,
+		Handler:  HandleEntryList,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/entries",
+		Summary:  "Create an entry",
+		Scope:    auth.ScopeWrite,
+		Body:     CreateEntryRequest{},
+		Response: Entry{},
+		Status:   http.StatusCreated,
+		Handler:  HandleEntryCreate,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/entries/{id}",
+		Summary:  "Get an entry",
+		Scope:    auth.ScopeRead,
+		Response: Entry{},
+		Status:   http.StatusOK,
+		Handler:  HandleEntryShow,
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/entries/{id}",
+		Summary:  "Update the given fields of an entry",
+		Scope:    auth.ScopeWrite,
+		Body:     UpdateEntryRequest{},
+		Response: Entry{},
+		Status:   http.StatusOK,
+		Handler:  HandleEntryUpdate,
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/entries/{id}",
+		Summary: "Delete an entry",
+		Scope:   auth.ScopeWrite,
+		Status:  http.StatusNoContent,
+		Handler: HandleEntryDelete,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/decks",
+		Summary:  "List the decks of the current user",
+		Scope:    auth.ScopeRead,
+		Query:    paginationParams,
+		Response: Deck{},
+		Status:   http.StatusOK,
+		List:     true,
+		Handler:  HandleDeckList,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/decks",
+		Summary:  "Create a deck",
+		Scope:    auth.ScopeWrite,
+		Body:     CreateDeckRequest{},
+		Response: Deck{},
+		Status:   http.StatusCreated,
+		Handler:  HandleDeckCreate,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/decks/{id}",
+		Summary:  "Get a deck",
+		Scope:    auth.ScopeRead,
+		Response: Deck{},
+		Status:   http.StatusOK,
+		Handler:  HandleDeckShow,
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/decks/{id}",
+		Summary:  "Update the given fields of a deck",
+		Scope:    auth.ScopeWrite,
+		Body:     UpdateDeckRequest{},
+		Response: Deck{},
+		Status:   http.StatusOK,
+		Handler:  HandleDeckUpdate,
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/decks/{id}",
+		Summary: "Delete a deck, its entries are kept without a deck",
+		Scope:   auth.ScopeWrite,
+		Status:  http.StatusNoContent,
+		Handler: HandleDeckDelete,
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/reviews/due",
+		Summary: "List the entries that are due for review, most overdue first",
+		Scope:   auth.ScopeRead,
+		Query: []Param{
+			paginationParams[0],
+			paginationParams[1],
+			deckParamDoc,
+		},
+		Response: Entry{},
+		Status:   http.StatusOK,
+		List:     true,
+		Handler:  HandleReviewDue,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/reviews",
+		Summary:  "Submit the answer to an entry and schedule its next review",
+		Scope:    auth.ScopeWrite,
+		Body:     CreateReviewRequest{},
+		Response: Review{},
+		Status:   http.StatusCreated,
+		Handler:  HandleReviewCreate,
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/uploads",
+		Summary:     "Extract a new entry from an uploaded picture",
+		Scope:       auth.ScopeUpload,
+		Multipart:   true,
+		Response:    Entry{},
+		Status:      http.StatusCreated,
+		RateLimited: true,
+		Handler:     HandleUploadCreate,
+	},
+}
+
+func InitializeRoutes(router chi.Router, uploadLimiter *ratelimit.Limiter) {
+	authConfig := kit.AuthenticationConfig{
+		AuthFunc:    auth.AuthenticateAPIRequest,
+		RedirectURL: "/login",
+	}
+
+	router.Route("/api/v1", func(api chi.Router) {
+		api.NotFound(handler(func(kit *kit.Kit) error {
+			return errNotFound("no such endpoint")
+		}))
+		api.MethodNotAllowed(handler(func(kit *kit.Kit) error {
+			return Error{
+				Status:  http.StatusMethodNotAllowed,
+				Code:    "method_not_allowed",
+				Message: "method not allowed",
+			}
+		}))
+		api.Get("/openapi.json", handler(HandleOpenAPI))
+
+		api.Group(func(api chi.Router) {
+			// The API never redirects to the login page, unauthenticated
+			// requests are rejected by requireAuth instead.
+			api.Use(kit.WithAuthentication(authConfig, false))
+			api.Use(requireAuth)
+			rateLimit := uploadLimiter.Middleware(auth.RateLimitKey, func(w http.ResponseWriter, r *http.Request) {
+				writeError(&kit.Kit{Response: w, Request: r}, Error{
+					Status:  http.StatusTooManyRequests,
+					Code:    "rate_limited",
+					Message: "too many uploads, try again later",
+				})
+			})
+			for _, e := range endpoints {
+				route := api.With(requireScope(e.Scope))
+				if e.RateLimited {
+					route = route.With(rateLimit)
+				}
+				route.Method(e.Method, e.Path, handler(e.Handler))
+			}
+		})
+	})
+}
+
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kit := &kit.Kit{Response: w, Request: r}
+		if !kit.Auth().Check() {
+			writeError(kit, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			kit := &kit.Kit{Response: w, Request: r}
+			if !kit.Auth().(auth.Auth).Can(scope) {
+				writeError(kit, Error{
+					Status:  http.StatusForbidden,
+					Code:    "insufficient_scope",
+					Message: "the API token is missing the " + scope + " scope",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
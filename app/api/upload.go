@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/metrics"
+	"smartquiz/app/types"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+const maxUploadSize = 10 << 20
+
+// HandleUploadCreate runs the uploaded picture through the AI extraction
+// and stores the result as a new entry of the current user. Send
+// "Cache-Control: no-cache" to extract the picture again instead of
+// using the cached result of an earlier upload.
+func HandleUploadCreate(kit *kit.Kit) error {
+	outcome := "error"
+	defer func() {
+		metrics.Uploads.WithLabelValues("api", outcome).Inc()
+	}()
+
+	kit.Request.Body = http.MaxBytesReader(kit.Response, kit.Request.Body, maxUploadSize)
+	if err := kit.Request.ParseMultipartForm(maxUploadSize); err != nil {
+		outcome = "invalid"
+		return errBadRequest("invalid multipart form: " + err.Error())
+	}
+	file, _, err := kit.Request.FormFile("file")
+	if err != nil {
+		outcome = "invalid"
+		return errValidation(map[string]string{"file": "is required"})
+	}
+	defer file.Close()
+
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	if err := ai.CheckQuota(kit.Request.Context(), userID(kit)); err != nil {
+		var quotaErr *ai.QuotaError
+		if errors.As(err, &quotaErr) {
+			outcome = "quota_exceeded"
+			return Error{Status: http.StatusTooManyRequests, Code: "quota_exceeded", Message: quotaErr.Error()}
+		}
+		return err
+	}
+	ctx := kit.Request.Context()
+	if kit.Request.Header.Get("Cache-Control") == "no-cache" {
+		ctx = ai.WithoutCache(ctx)
+	}
+	pictureReq := ai.PictureRequest{
+		UserID:   userID(kit),
+		Image:    fileBytes,
+		Language: kit.Request.FormValue("language"),
+	}
+	visionRes, err := ai.ReadPicture(ctx, pictureReq)
+	if err != nil {
+		ai.RecordFailedUpload(ctx, "api", pictureReq, err)
+		return err
+	}
+	word := types.GermanWord{
+		UserID:        userID(kit),
+		Example:       visionRes.Example,
+		GermanWord:    visionRes.Glossary,
+		Definition:    visionRes.Definition,
+		PromptVersion: visionRes.PromptVersion,
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&word).Error; err != nil {
+		return err
+	}
+	outcome = "success"
+	return writeData(kit, http.StatusCreated, newEntry(word))
+}
@@ -0,0 +1,275 @@
+// Package backup writes and restores backups of the SQLite database.
+//
+// A backup is a gzipped tar archive. Its first entry is manifest.json,
+// which lists the other entries with their size and SHA-256 checksum.
+// The database is copied with VACUUM INTO, which gives a consistent
+// snapshot while the server keeps running.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"smartquiz/app/db"
+
+	"gorm.io/gorm"
+)
+
+// Format is the version of the archive layout written by Create.
+const Format = 1
+
+const (
+	manifestName = "manifest.json"
+	databaseName = "database.sqlite3"
+)
+
+// Manifest describes the content of a backup.
+type Manifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Driver    string    `json:"driver"`
+	// SchemaVersion is the last migration applied to the database.
+	SchemaVersion int64  `json:"schema_version"`
+	Files         []File `json:"files"`
+}
+
+// File is an entry of the archive.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Create writes a backup of the database to w. Only SQLite databases
+// are supported, Postgres and MySQL come with their own tools.
+func Create(ctx context.Context, instance *gorm.DB, w io.Writer) (Manifest, error) {
+	driver := db.Driver(instance)
+	if driver != db.DriverSqlite3 {
+		return Manifest{}, fmt.Errorf("backups of %s databases are not supported, use the tools of the database", driver)
+	}
+	version, err := db.Version(instance)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "smartquiz-backup")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, databaseName)
+	if err := instance.WithContext(ctx).Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return Manifest{}, fmt.Errorf("failed to copy the database: %w", err)
+	}
+	file, err := checksum(snapshot)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{
+		Format:        Format,
+		CreatedAt:     time.Now().UTC(),
+		Driver:        driver,
+		SchemaVersion: version,
+		Files:         []File{file},
+	}
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = writeEntry(tw, manifestName, int64(len(b)), manifest.CreatedAt, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	err = writeEntry(tw, databaseName, file.Size, manifest.CreatedAt, func(w io.Writer) error {
+		f, err := os.Open(snapshot)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, modTime time.Time, write func(io.Writer) error) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0o600,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	return write(tw)
+}
+
+func checksum(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: filepath.Base(name), Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Verify checks the archive without restoring it: the manifest, the
+// checksums and the integrity of the database.
+func Verify(r io.Reader) (Manifest, error) {
+	dir, err := os.MkdirTemp("", "smartquiz-restore")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dir)
+	return extract(r, dir)
+}
+
+// Restore verifies the archive and replaces the SQLite database at path
+// with the one of the backup. The server must not be running.
+func Restore(r io.Reader, path string) (Manifest, error) {
+	// Extract next to the database, so it can be renamed into place.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".restore")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := extract(r, dir)
+	if err != nil {
+		return manifest, err
+	}
+	// The journal of the replaced database must not be applied to the
+	// restored one.
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return manifest, err
+		}
+	}
+	return manifest, os.Rename(filepath.Join(dir, databaseName), path)
+}
+
+// extract reads the archive into dir and validates it.
+func extract(r io.Reader, dir string) (Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return Manifest{}, errors.New("not a backup archive: the manifest is missing")
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Format != Format {
+		return manifest, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	if manifest.Driver != db.DriverSqlite3 {
+		return manifest, fmt.Errorf("unsupported database driver %s", manifest.Driver)
+	}
+	migrations, err := db.Migrations(db.DriverSqlite3)
+	if err != nil {
+		return manifest, err
+	}
+	if n := len(migrations); n > 0 && manifest.SchemaVersion > migrations[n-1].Version {
+		return manifest, fmt.Errorf("the backup has schema version %d, this version of smartquiz only knows %d", manifest.SchemaVersion, migrations[n-1].Version)
+	}
+
+	expected := make(map[string]File, len(manifest.Files))
+	for _, f := range manifest.Files {
+		// The names are joined with dir, names other than the database
+		// could write anywhere, e.g. ../../etc/cron.d/x.
+		if f.Name != databaseName {
+			return manifest, fmt.Errorf("unexpected file %q in the manifest", f.Name)
+		}
+		expected[f.Name] = f
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("corrupt archive: %w", err)
+		}
+		file, ok := expected[hdr.Name]
+		if !ok {
+			return manifest, fmt.Errorf("%s is not listed in the manifest", hdr.Name)
+		}
+		delete(expected, hdr.Name)
+		if err := extractFile(tr, filepath.Join(dir, file.Name), file); err != nil {
+			return manifest, err
+		}
+	}
+	for name := range expected {
+		return manifest, fmt.Errorf("%s is missing in the archive", name)
+	}
+	return manifest, checkDatabase(filepath.Join(dir, databaseName))
+}
+
+func extractFile(r io.Reader, name string, file File) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	if n != file.Size || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("checksum of %s doesn't match the manifest", file.Name)
+	}
+	return f.Close()
+}
+
+// checkDatabase runs the SQLite integrity check on the database file.
+func checkDatabase(name string) error {
+	conn, err := sql.Open("sqlite3", "file:"+name+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("the database of the backup is corrupt: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("the database of the backup is corrupt: %s", result)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/dbtest"
+	"smartquiz/app/types"
+
+	"gorm.io/gorm/logger"
+)
+
+func newBackup(t *testing.T) []byte {
+	t.Helper()
+	instance := dbtest.New(t)
+	if db.Driver(instance) != db.DriverSqlite3 {
+		t.Skip("backups only support sqlite3")
+	}
+	if err := instance.Create(&types.GermanWord{GermanWord: "das Haus"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	manifest, err := Create(context.Background(), instance, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.SchemaVersion == 0 || len(manifest.Files) != 1 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	return buf.Bytes()
+}
+
+func TestRestore(t *testing.T) {
+	archive := newBackup(t)
+	path := filepath.Join(t.TempDir(), "app_db")
+	if err := os.WriteFile(path, []byte("replaced"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(bytes.NewReader(archive), path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := db.Open(db.Config{Driver: db.DriverSqlite3, Name: path, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := restored.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	var word types.GermanWord
+	if err := restored.First(&word).Error; err != nil {
+		t.Fatal(err)
+	}
+	if word.GermanWord != "das Haus" {
+		t.Errorf("expected das Haus, got %q", word.GermanWord)
+	}
+	if pending, err := db.Pending(restored); err != nil || len(pending) != 0 {
+		t.Errorf("expected no pending migrations, got %d, %v", len(pending), err)
+	}
+}
+
+// rewrite returns the archive with the content of the entry name
+// replaced by change.
+func rewrite(t *testing.T, archive []byte, name string, change func([]byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == name {
+			b = change(b)
+			hdr.Size = int64(len(b))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(b)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestVerify(t *testing.T) {
+	archive := newBackup(t)
+	if _, err := Verify(bytes.NewReader(archive)); err != nil {
+		t.Fatalf("expected a valid backup, got %v", err)
+	}
+
+	tests := map[string]struct {
+		archive []byte
+		err     string
+	}{
+		"not an archive": {[]byte("hello"), "not a backup archive"},
+		"corrupt database": {rewrite(t, archive, databaseName, func(b []byte) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}), "checksum of database.sqlite3"},
+		"newer schema": {rewrite(t, archive, manifestName, func(b []byte) []byte {
+			return bytes.Replace(b, []byte(`"schema_version": `), []byte(`"schema_version": 9`), 1)
+		}), "schema version"},
+		"path traversal": {rewrite(t, archive, manifestName, func(b []byte) []byte {
+			return bytes.Replace(b, []byte(`"`+databaseName+`"`), []byte(`"../../`+databaseName+`"`), 1)
+		}), "unexpected file"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Verify(bytes.NewReader(tt.archive))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(dir, FileName(start.AddDate(0, 0, i)))
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := Prune(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{FileName(start), FileName(start.AddDate(0, 0, 1))}
+	if strings.Join(deleted, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v to be deleted, got %v", expected, deleted)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("expected other files to be kept")
+	}
+}
@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// filePrefix and fileSuffix surround the time of the scheduled backups,
+// Prune only deletes files named like this.
+const (
+	filePrefix = "smartquiz-"
+	fileSuffix = ".tar.gz"
+)
+
+// FileName returns the name of a backup created at t. Names sort by
+// time.
+func FileName(t time.Time) string {
+	return filePrefix + t.UTC().Format("20060102T150405Z") + fileSuffix
+}
+
+// WriteFile writes a backup into dir. The file only appears under its
+// final name once it's complete.
+func WriteFile(ctx context.Context, instance *gorm.DB, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, ".backup-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	manifest, err := Create(ctx, instance, f)
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, FileName(manifest.CreatedAt))
+	return name, os.Rename(f.Name(), name)
+}
+
+// Prune deletes all but the newest keep backups in dir.
+func Prune(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+	sort.Strings(names)
+	var deleted []string
+	var errs []error
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, name)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// Schedule writes a backup into dir every interval and keeps the newest
+// keep backups, until the context is cancelled.
+func Schedule(ctx context.Context, instance *gorm.DB, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		name, err := WriteFile(ctx, instance, dir)
+		if err != nil {
+			slog.Error("failed to back up the database", "err", err)
+			continue
+		}
+		slog.Info("backed up the database", "file", name)
+		deleted, err := Prune(dir, keep)
+		if err != nil {
+			slog.Error("failed to delete old backups", "err", err)
+		}
+		if len(deleted) > 0 {
+			slog.Info("deleted old backups", "count", len(deleted))
+		}
+	}
+}
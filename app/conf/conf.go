@@ -1,3 +1,478 @@
+// Package conf holds the typed configuration of the application.
+//
+// Every setting is read from an environment variable. The same variables
+// can be set in an optional .env style file and overridden with flags,
+// e.g. DB_DRIVER becomes -db-driver. Run "app config check" to see the
+// effective configuration and every missing or invalid setting.
 package conf
 
-// Here goes your application config
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultFile is the configuration file read if it exists and no other
+// file is given with -config or CONFIG_FILE.
+const DefaultFile = ".env"
+
+// Config is the configuration of the application. The struct tags
+// describe every setting:
+//
+//	env      name of the environment variable
+//	default  value if the variable is not set
+//	secret   the value is redacted when printed
+//	required the variable must be set
+//	min      minimum of a number
+//	oneof    space separated list of the allowed values
+type Config struct {
+	Env        string `env:"SUPERKIT_ENV" default:"development" oneof:"development production"`
+	ListenAddr string `env:"HTTP_LISTEN_ADDR" default:":3000"`
+	// AppURL is the public URL used in links of emails and redirects.
+	AppURL string `env:"APP_URL" default:"http://localhost:7331"`
+	// Secret signs the session cookies and tokens, at least 32 characters.
+	Secret string `env:"SUPERKIT_SECRET" secret:"true" required:"true"`
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For, only
+	// enable it behind a reverse proxy.
+	TrustProxyHeaders      bool `env:"TRUST_PROXY_HEADERS" default:"false"`
+	UploadRateLimitPerHour int  `env:"UPLOAD_RATE_LIMIT_PER_HOUR" default:"30" min:"1"`
+
+	// Timeouts of the HTTP server, zero disables a timeout. Uploads wait
+	// for the AI provider within the request, so the write timeout has
+	// to be longer than a vision call.
+	ReadTimeout  time.Duration `env:"HTTP_READ_TIMEOUT" default:"1m" min:"0"`
+	WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"2m" min:"0"`
+	IdleTimeout  time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"2m" min:"0"`
+	// ShutdownTimeout is how long running requests and event handlers
+	// may take to finish when the server is stopped.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s" min:"0"`
+
+	LogLevel  string `env:"LOG_LEVEL" default:"info" oneof:"debug info warn error"`
+	LogFormat string `env:"LOG_FORMAT" default:"text" oneof:"text json"`
+	// MetricsToken protects /metrics, scrapers send it as bearer token.
+	MetricsToken string `env:"METRICS_TOKEN" secret:"true"`
+	// TracingExporter sends the OpenTelemetry spans to an OTLP/HTTP
+	// collector at TracingEndpoint or prints them to stdout.
+	TracingExporter string `env:"TRACING_EXPORTER" default:"none" oneof:"none otlp stdout"`
+	// TracingEndpoint is the URL of the collector, e.g.
+	// http://localhost:4318, empty uses the OTEL_EXPORTER_OTLP_* variables.
+	TracingEndpoint string `env:"TRACING_ENDPOINT"`
+	// TracingSampleRatio is the share of the requests that are traced.
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	DB     DB
+	Auth   Auth
+	Mail   Mail
+	AI     AI
+	Backup Backup
+	Demo   Demo
+
+	// problems found while loading, reported by Check.
+	problems []Problem
+}
+
+type DB struct {
+	Driver          string        `env:"DB_DRIVER" default:"sqlite3" oneof:"sqlite3 postgres mysql"`
+	Name            string        `env:"DB_NAME" default:"app_db"`
+	Host            string        `env:"DB_HOST"`
+	Port            string        `env:"DB_PORT"`
+	User            string        `env:"DB_USER"`
+	Password        string        `env:"DB_PASSWORD" secret:"true"`
+	Params          string        `env:"DB_PARAMS"`
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"0" min:"0"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"0" min:"0"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"0s" min:"0"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"0s" min:"0"`
+	// AutoMigrate applies missing migrations on start.
+	AutoMigrate bool `env:"DB_AUTO_MIGRATE" default:"false"`
+}
+
+type Auth struct {
+	RedirectAfterLogin string `env:"SUPERKIT_AUTH_REDIRECT_AFTER_LOGIN" default:"/profile"`
+	// SkipVerify lets users log in without verifying their email.
+	SkipVerify                      bool `env:"SUPERKIT_AUTH_SKIP_VERIFY" default:"false"`
+	SessionExpiryInHours            int  `env:"SUPERKIT_AUTH_SESSION_EXPIRY_IN_HOURS" default:"48" min:"1"`
+	PasswordResetExpiryInHours      int  `env:"SUPERKIT_AUTH_PASSWORD_RESET_EXPIRY_IN_HOURS" default:"1" min:"1"`
+	EmailVerificationExpiryInHours  int  `env:"SUPERKIT_AUTH_EMAIL_VERIFICATION_EXPIRY_IN_HOURS" default:"1" min:"1"`
+	SessionCleanupIntervalInMinutes int  `env:"SUPERKIT_AUTH_SESSION_CLEANUP_INTERVAL_IN_MINUTES" default:"60" min:"1"`
+	AccountPurgeIntervalInMinutes   int  `env:"SUPERKIT_AUTH_ACCOUNT_PURGE_INTERVAL_IN_MINUTES" default:"60" min:"1"`
+	AccountDeletionGraceInDays      int  `env:"SUPERKIT_AUTH_ACCOUNT_DELETION_GRACE_IN_DAYS" default:"14" min:"1"`
+	LoginAttemptsPerMinute          int  `env:"SUPERKIT_AUTH_LOGIN_ATTEMPTS_PER_MINUTE" default:"10" min:"1"`
+	MaxFailedLogins                 int  `env:"SUPERKIT_AUTH_MAX_FAILED_LOGINS" default:"5" min:"1"`
+	LockoutInSeconds                int  `env:"SUPERKIT_AUTH_LOCKOUT_IN_SECONDS" default:"60" min:"1"`
+
+	// Single sign-on with OpenID Connect, enabled by setting the issuer.
+	OIDCName         string `env:"SUPERKIT_AUTH_OIDC_NAME" default:"SSO"`
+	OIDCIssuer       string `env:"SUPERKIT_AUTH_OIDC_ISSUER"`
+	OIDCClientID     string `env:"SUPERKIT_AUTH_OIDC_CLIENT_ID"`
+	OIDCClientSecret string `env:"SUPERKIT_AUTH_OIDC_CLIENT_SECRET" secret:"true"`
+	// OIDCRedirectURL defaults to APP_URL/login/oidc/callback.
+	OIDCRedirectURL string `env:"SUPERKIT_AUTH_OIDC_REDIRECT_URL"`
+}
+
+type Mail struct {
+	Driver string `env:"MAIL_DRIVER" default:"outbox" oneof:"outbox smtp"`
+	From   string `env:"MAIL_FROM" default:"smartquiz <no-reply@localhost>"`
+	// SMTP settings, only used by the smtp driver.
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT" default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD" secret:"true"`
+	// OutboxDir is where the outbox driver writes its .eml files.
+	OutboxDir string `env:"MAIL_OUTBOX_DIR"`
+}
+
+type Backup struct {
+	// Dir receives the scheduled backups of the SQLite database, empty
+	// disables them.
+	Dir      string        `env:"BACKUP_DIR"`
+	Interval time.Duration `env:"BACKUP_INTERVAL" default:"24h" min:"1"`
+	// Keep is how many scheduled backups are kept, older ones are
+	// deleted.
+	Keep int `env:"BACKUP_KEEP" default:"7" min:"1"`
+}
+
+type Demo struct {
+	// Enabled resets the demo account on start and daily at ResetAt,
+	// and answers uploads with demo words instead of calling the AI
+	// provider.
+	Enabled  bool   `env:"DEMO_MODE" default:"false"`
+	Email    string `env:"DEMO_EMAIL" default:"demo@example.com"`
+	Password string `env:"DEMO_PASSWORD" default:"Demo!quiz1"`
+	// ResetAt is the time of day in UTC, e.g. 03:00.
+	ResetAt string `env:"DEMO_RESET_AT" default:"03:00"`
+}
+
+type AI struct {
+	APIKey string `env:"API_KEY" secret:"true"`
+	// APIKeyFile is read if API_KEY is empty, e.g. a Docker secret.
+	APIKeyFile string `env:"API_KEY_FILE"`
+	// Prices overrides the model prices, see ParsePrices.
+	Prices string `env:"AI_PRICES"`
+	// Token quotas per user, zero means unlimited.
+	DailyTokenQuota   int64 `env:"AI_DAILY_TOKEN_QUOTA" default:"0" min:"0"`
+	MonthlyTokenQuota int64 `env:"AI_MONTHLY_TOKEN_QUOTA" default:"0" min:"0"`
+	// CacheTTLInHours of the response cache, zero disables it.
+	CacheTTLInHours               int `env:"AI_CACHE_TTL_IN_HOURS" default:"720" min:"0"`
+	CacheCleanupIntervalInMinutes int `env:"AI_CACHE_CLEANUP_INTERVAL_IN_MINUTES" default:"60" min:"1"`
+}
+
+// Problem is a missing or invalid setting.
+type Problem struct {
+	Key     string
+	Message string
+	// Warning problems don't stop the server from starting.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s: %s %s", level, p.Key, p.Message)
+}
+
+// setting is a field of the Config with its tags.
+type setting struct {
+	field reflect.StructField
+	value reflect.Value
+}
+
+func (s setting) key() string {
+	return s.field.Tag.Get("env")
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.key()), "_", "-")
+}
+
+// settings returns all tagged fields of v, including those of the nested
+// structs.
+func settings(v reflect.Value) []setting {
+	var list []setting
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			list = append(list, settings(value)...)
+			continue
+		}
+		if len(field.Tag.Get("env")) > 0 {
+			list = append(list, setting{field: field, value: value})
+		}
+	}
+	return list
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (s setting) set(raw string) error {
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 30s or 5m, got %q", raw)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Int || s.value.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		s.value.SetInt(n)
+	case s.value.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		s.value.SetFloat(f)
+	default:
+		panic("conf: unsupported type of " + s.key())
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	if s.value.Type() == durationType {
+		return time.Duration(s.value.Int()).String()
+	}
+	return fmt.Sprint(s.value.Interface())
+}
+
+// Load reads the configuration. Values are taken from, in increasing
+// order of precedence, the defaults, the configuration file, the
+// environment and the flags in args. The file is given by -config or
+// CONFIG_FILE, DefaultFile is read if it exists.
+//
+// Invalid values don't fail the load, they are reported by Check.
+func Load(args []string) (*Config, error) {
+	c := &Config{}
+	list := settings(reflect.ValueOf(c).Elem())
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "configuration `file` with KEY=value lines")
+	flags := make(map[string]*string, len(list))
+	for _, s := range list {
+		flags[s.key()] = fs.String(s.flagName(), "", "overrides "+s.key())
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	values := make(map[string]string)
+	if len(*file) > 0 || fileExists(DefaultFile) {
+		name := *file
+		if len(name) == 0 {
+			name = DefaultFile
+		}
+		fileValues, err := godotenv.Read(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the configuration file: %w", err)
+		}
+		values = fileValues
+	}
+
+	for _, s := range list {
+		raw, ok := s.field.Tag.Lookup("default")
+		if v, set := values[s.key()]; set {
+			raw, ok = v, true
+		}
+		if v := os.Getenv(s.key()); len(v) > 0 {
+			raw, ok = v, true
+		}
+		if explicit[s.flagName()] {
+			raw, ok = *flags[s.key()], true
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(raw); err != nil {
+			c.problems = append(c.problems, Problem{Key: s.key(), Message: err.Error()})
+		}
+	}
+
+	if len(c.AI.APIKey) == 0 && len(c.AI.APIKeyFile) > 0 {
+		b, err := os.ReadFile(c.AI.APIKeyFile)
+		if err != nil {
+			c.problems = append(c.problems, Problem{Key: "API_KEY_FILE", Message: err.Error()})
+		}
+		c.AI.APIKey = strings.TrimSpace(string(b))
+	}
+	if len(c.Auth.OIDCRedirectURL) == 0 {
+		c.Auth.OIDCRedirectURL = strings.TrimRight(c.AppURL, "/") + "/login/oidc/callback"
+	}
+	return c, nil
+}
+
+// Check validates the configuration and returns every problem found.
+func (c *Config) Check() []Problem {
+	problems := append([]Problem{}, c.problems...)
+	add := func(key, msg string, warning bool) {
+		problems = append(problems, Problem{Key: key, Message: msg, Warning: warning})
+	}
+	for _, s := range settings(reflect.ValueOf(c).Elem()) {
+		if s.field.Tag.Get("required") == "true" && s.value.IsZero() {
+			add(s.key(), "is required", false)
+			continue
+		}
+		if oneof, ok := s.field.Tag.Lookup("oneof"); ok {
+			allowed := strings.Fields(oneof)
+			if !contains(allowed, s.value.String()) {
+				add(s.key(), fmt.Sprintf("must be one of %s, got %q", strings.Join(allowed, ", "), s.value.String()), false)
+			}
+		}
+		if min, ok := s.field.Tag.Lookup("min"); ok {
+			n, _ := strconv.ParseInt(min, 10, 64)
+			if s.value.Int() < n {
+				add(s.key(), fmt.Sprintf("must be at least %d", n), false)
+			}
+		}
+	}
+
+	if len(c.Secret) > 0 && len(c.Secret) < 32 {
+		add("SUPERKIT_SECRET", "must be at least 32 characters long", false)
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO", "must be between 0 and 1", false)
+	}
+	if len(c.Backup.Dir) > 0 && c.DB.Driver != "sqlite3" {
+		add("BACKUP_DIR", "scheduled backups only support sqlite3, use the tools of the database", false)
+	}
+	if _, err := ParsePrices(c.AI.Prices); err != nil {
+		add("AI_PRICES", err.Error(), false)
+	}
+	if _, err := time.Parse("15:04", c.Demo.ResetAt); err != nil {
+		add("DEMO_RESET_AT", "must be a time of day like 03:00", false)
+	}
+	if c.Mail.Driver == "smtp" && len(c.Mail.SMTPHost) == 0 {
+		add("SMTP_HOST", "is required by the smtp mail driver", false)
+	}
+	if len(c.Auth.OIDCIssuer) > 0 && len(c.Auth.OIDCClientID) == 0 {
+		add("SUPERKIT_AUTH_OIDC_CLIENT_ID", "is required for single sign-on", false)
+	}
+	if c.DB.Driver != "sqlite3" && len(c.DB.Host) == 0 {
+		add("DB_HOST", "is not set, connecting to localhost", true)
+	}
+	if len(c.AI.APIKey) == 0 && !c.Demo.Enabled {
+		add("API_KEY", "is not set, uploads will fail", true)
+	}
+	if c.Env == "production" {
+		if strings.Contains(c.AppURL, "localhost") {
+			add("APP_URL", "points to localhost in production", true)
+		}
+		if c.Demo.Enabled {
+			add("DEMO_MODE", "resets the demo account and disables the AI provider in production", true)
+		}
+		if c.Mail.Driver == "outbox" {
+			add("MAIL_DRIVER", "outbox does not deliver any mail, use smtp in production", false)
+		}
+		if c.Auth.SkipVerify {
+			add("SUPERKIT_AUTH_SKIP_VERIFY", "lets users log in without verifying their email in production", true)
+		}
+	}
+	return problems
+}
+
+// Err returns an error listing the problems of Check that aren't
+// warnings, or nil.
+func (c *Config) Err() error {
+	var errs []error
+	for _, p := range c.Check() {
+		if !p.Warning {
+			errs = append(errs, errors.New(p.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes the configuration as KEY=value lines with the secrets
+// redacted.
+func (c *Config) Print(w io.Writer) {
+	for _, s := range settings(reflect.ValueOf(c).Elem()) {
+		value := s.String()
+		if s.field.Tag.Get("secret") == "true" && len(value) > 0 {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(w, "%s=%s\n", s.key(), value)
+	}
+}
+
+// Secrets returns the values of the secret settings that are set, they
+// must never be logged.
+func (c *Config) Secrets() []string {
+	var secrets []string
+	for _, s := range settings(reflect.ValueOf(c).Elem()) {
+		if s.field.Tag.Get("secret") == "true" && !s.value.IsZero() {
+			secrets = append(secrets, s.String())
+		}
+	}
+	return secrets
+}
+
+// Export sets the environment variables superkit reads itself.
+func (c *Config) Export() {
+	os.Setenv("SUPERKIT_ENV", c.Env)
+	os.Setenv("SUPERKIT_SECRET", c.Secret)
+}
+
+var (
+	mu      sync.Mutex
+	current *Config
+)
+
+// Get returns the configuration set with Set. Until then, e.g. in tests,
+// it's loaded from the environment only.
+func Get() *Config {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		c, err := Load(nil)
+		if err != nil {
+			panic(err)
+		}
+		current = c
+	}
+	return current
+}
+
+// Set makes c the configuration returned by Get.
+func Set(c *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
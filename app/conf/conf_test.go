@@ -0,0 +1,132 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.env")
+	content := "DB_NAME=from_file\nDB_HOST=from_file\nDB_PORT=from_file\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_HOST", "from_env")
+	t.Setenv("DB_PORT", "from_env")
+
+	c, err := Load([]string{"-config", file, "-db-port", "from_flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, got, expected string
+	}{
+		{"DB_DRIVER", c.DB.Driver, "sqlite3"},
+		{"DB_NAME", c.DB.Name, "from_file"},
+		{"DB_HOST", c.DB.Host, "from_env"},
+		{"DB_PORT", c.DB.Port, "from_flag"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("expected %s to be %q, got %q", test.key, test.expected, test.got)
+		}
+	}
+}
+
+func TestLoadTypes(t *testing.T) {
+	t.Setenv("DB_CONN_MAX_LIFETIME", "30m")
+	t.Setenv("SUPERKIT_AUTH_SKIP_VERIFY", "true")
+	t.Setenv("AI_DAILY_TOKEN_QUOTA", "100000")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DB.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("expected 30m, got %s", c.DB.ConnMaxLifetime)
+	}
+	if !c.Auth.SkipVerify {
+		t.Error("expected SkipVerify to be set")
+	}
+	if c.AI.DailyTokenQuota != 100000 {
+		t.Errorf("expected a quota of 100000, got %d", c.AI.DailyTokenQuota)
+	}
+	if c.TracingSampleRatio != 0.25 {
+		t.Errorf("expected a sample ratio of 0.25, got %v", c.TracingSampleRatio)
+	}
+	if c.Auth.OIDCRedirectURL != "http://localhost:7331/login/oidc/callback" {
+		t.Errorf("unexpected default redirect URL %s", c.Auth.OIDCRedirectURL)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("SUPERKIT_SECRET", "")
+	t.Setenv("DB_DRIVER", "oracle")
+	t.Setenv("SUPERKIT_AUTH_MAX_FAILED_LOGINS", "0")
+	t.Setenv("AI_MONTHLY_TOKEN_QUOTA", "lots")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	t.Setenv("SUPERKIT_ENV", "production")
+	t.Setenv("MAIL_DRIVER", "outbox")
+	t.Setenv("AI_PRICES", "gpt-4o=cheap")
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(map[string]bool)
+	for _, p := range c.Check() {
+		if !p.Warning {
+			errs[p.Key] = true
+		}
+	}
+	for _, key := range []string{"SUPERKIT_SECRET", "DB_DRIVER", "SUPERKIT_AUTH_MAX_FAILED_LOGINS", "AI_MONTHLY_TOKEN_QUOTA", "TRACING_SAMPLE_RATIO", "MAIL_DRIVER", "AI_PRICES"} {
+		if !errs[key] {
+			t.Errorf("expected a problem with %s", key)
+		}
+	}
+	if c.Err() == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParsePrices(t *testing.T) {
+	prices, err := ParsePrices("gpt-4o-mini=0.15:0.60, gpt-4o=2.50:10,text-embedding-3-small=0.02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices["gpt-4o"] != (Price{Prompt: 2.50, Completion: 10}) {
+		t.Fatalf("unexpected gpt-4o price %+v", prices["gpt-4o"])
+	}
+	if prices["text-embedding-3-small"] != (Price{Prompt: 0.02}) {
+		t.Fatalf("unexpected embedding price %+v", prices["text-embedding-3-small"])
+	}
+	if _, err := ParsePrices("gpt-4o"); err == nil {
+		t.Fatal("expected an error for a price without model")
+	}
+	if _, err := ParsePrices("gpt-4o=cheap"); err == nil {
+		t.Fatal("expected an error for an invalid number")
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("SUPERKIT_SECRET", "a-secret-that-is-long-enough-for-superkit")
+	t.Setenv("DB_PASSWORD", "hunter2")
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	c.Print(&b)
+	out := b.String()
+	for _, secret := range []string{"a-secret-that-is-long-enough-for-superkit", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q was printed", secret)
+		}
+	}
+	if !strings.Contains(out, "DB_PASSWORD=[redacted]\n") {
+		t.Error("expected DB_PASSWORD to be redacted")
+	}
+}
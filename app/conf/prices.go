@@ -0,0 +1,42 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price of an AI model in US dollars per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// ParsePrices parses AI_PRICES, a comma separated list of
+// model=prompt:completion prices, e.g. "gpt-4o-mini=0.15:0.60,gpt-4o=2.50:10".
+func ParsePrices(s string) (map[string]Price, error) {
+	prices := make(map[string]Price)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		model, price, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid price %q, expected model=prompt:completion", entry)
+		}
+		prompt, completion, _ := strings.Cut(price, ":")
+		p, err := strconv.ParseFloat(prompt, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt price of %s: %w", model, err)
+		}
+		var c float64
+		if len(completion) > 0 {
+			if c, err = strconv.ParseFloat(completion, 64); err != nil {
+				return nil, fmt.Errorf("invalid completion price of %s: %w", model, err)
+			}
+		}
+		prices[strings.TrimSpace(model)] = Price{Prompt: p, Completion: c}
+	}
+	return prices, nil
+}
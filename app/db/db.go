@@ -1,40 +1,148 @@
 package db
 
 import (
-	"log"
-	"os"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/pkg/tracing"
 
 	"github.com/anthdm/superkit/db"
+	"github.com/go-sql-driver/mysql"
 
 	_ "github.com/mattn/go-sqlite3"
 
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Supported values of DB_DRIVER.
+const (
+	DriverSqlite3  = db.DriverSqlite3
+	DriverPostgres = "postgres"
+	DriverMysql    = db.DriverMysql
 )
 
+// Memory is the name of an in-memory SQLite database, e.g. for tests.
+const Memory = ":memory:"
+
 // By default this is a pre-configured Gorm DB instance.
 // Change this type based on the database package of your likings.
 var dbInstance *gorm.DB
 
-// Get returns the instantiated DB instance.
+// Get returns the DB instance set with Init or Set.
 func Get() *gorm.DB {
+	if dbInstance == nil {
+		panic("db: not initialized, call db.Init first")
+	}
 	return dbInstance
 }
 
-func init() {
-	// Create a default *sql.DB exposed by the superkit/db package
-	// based on the given configuration.
-	config := db.Config{
-		Driver:   os.Getenv("DB_DRIVER"),
-		Name:     os.Getenv("DB_NAME"),
-		Password: os.Getenv("DB_PASSWORD"),
-		User:     os.Getenv("DB_USER"),
-		Host:     os.Getenv("DB_HOST"),
+// Set replaces the DB instance returned by Get. Handlers and jobs use
+// the instance set at startup, tests inject their own with dbtest.
+func Set(instance *gorm.DB) {
+	dbInstance = instance
+}
+
+// Config of the database connection.
+type Config struct {
+	Driver string
+	// Name is the file of a SQLite database and the database name of
+	// Postgres and MySQL.
+	Name     string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	// Params are added to the connection string of Postgres and MySQL,
+	// e.g. "sslmode=disable".
+	Params string
+
+	// Connection pool, zero keeps the default of database/sql.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Logger replaces the slog logger of NewLogger if set.
+	Logger logger.Interface
+}
+
+// ConfigFromConf returns the database configuration of the application.
+func ConfigFromConf(c conf.DB) Config {
+	return Config{
+		Driver:          c.Driver,
+		Name:            c.Name,
+		Host:            c.Host,
+		Port:            c.Port,
+		User:            c.User,
+		Password:        c.Password,
+		Params:          c.Params,
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
 	}
-	dbinst, err := db.NewSQL(config)
-	if err != nil {
-		log.Fatal(err)
+}
+
+// DSN returns the connection string of a Postgres or MySQL database.
+func (c Config) DSN() (string, error) {
+	host := c.Host
+	if len(host) == 0 {
+		host = "localhost"
 	}
+	switch c.Driver {
+	case DriverPostgres:
+		port := c.Port
+		if len(port) == 0 {
+			port = "5432"
+		}
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     net.JoinHostPort(host, port),
+			Path:     "/" + c.Name,
+			RawQuery: c.Params,
+		}
+		return u.String(), nil
+	case DriverMysql:
+		port := c.Port
+		if len(port) == 0 {
+			port = "3306"
+		}
+		params, err := url.ParseQuery(c.Params)
+		if err != nil {
+			return "", fmt.Errorf("invalid DB_PARAMS: %w", err)
+		}
+		cfg := mysql.NewConfig()
+		cfg.User = c.User
+		cfg.Passwd = c.Password
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, port)
+		cfg.DBName = c.Name
+		cfg.ParseTime = true
+		cfg.Loc = time.UTC
+		cfg.Params = map[string]string{"charset": "utf8mb4"}
+		for k := range params {
+			cfg.Params[k] = params.Get(k)
+		}
+		return cfg.FormatDSN(), nil
+	default:
+		return "", fmt.Errorf("no connection string for driver %s", c.Driver)
+	}
+}
+
+// Open connects to the database described by config.
+func Open(config Config) (*gorm.DB, error) {
+	if config.Logger == nil {
+		config.Logger = NewLogger()
+	}
+	gormConfig := &gorm.Config{Logger: config.Logger}
 	// Based on the driver create the corresponding DB instance.
 	// By default, the SuperKit boilerplate comes with a pre-configured
 	// ORM called Gorm. https://gorm.io.
@@ -44,17 +152,79 @@ func init() {
 	// - uptrace bun -> https://bun.uptrace.dev
 	// - SQLC -> https://github.com/sqlc-dev/sqlc
 	// - gojet -> https://github.com/go-jet/jet
+	var dialector gorm.Dialector
 	switch config.Driver {
-	case db.DriverSqlite3:
-		dbInstance, err = gorm.Open(sqlite.New(sqlite.Config{
-			Conn: dbinst,
-		}))
-	case db.DriverMysql:
-		// ...
+	case DriverSqlite3:
+		// Create a default *sql.DB exposed by the superkit/db package
+		// based on the given configuration.
+		dbinst, err := db.NewSQL(db.Config{Driver: config.Driver, Name: config.Name})
+		if err != nil {
+			return nil, err
+		}
+		if config.Name == Memory {
+			// Every connection opens its own in-memory database, so
+			// there must only be one.
+			config.MaxOpenConns = 1
+		}
+		dialector = sqlite.New(sqlite.Config{Conn: dbinst})
+	case DriverPostgres:
+		dsn, err := config.DSN()
+		if err != nil {
+			return nil, err
+		}
+		dialector = postgres.Open(dsn)
+	case DriverMysql:
+		dsn, err := config.DSN()
+		if err != nil {
+			return nil, err
+		}
+		dialector = gormmysql.Open(dsn)
 	default:
-		log.Fatal("invalid driver:", config.Driver)
+		return nil, fmt.Errorf("invalid database driver %q, expected %s, %s or %s", config.Driver, DriverSqlite3, DriverPostgres, DriverMysql)
+	}
+
+	instance, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := instance.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
 	}
+	sqlDB, err := instance.DB()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+	return instance, nil
+}
+
+// Init opens the database and makes it the instance returned by Get.
+func Init(config Config) error {
+	instance, err := Open(config)
+	if err != nil {
+		return err
+	}
+	Set(instance)
+	return nil
+}
+
+// Driver returns the DB_DRIVER name of the database of the instance.
+func Driver(instance *gorm.DB) string {
+	switch name := instance.Dialector.Name(); name {
+	case "sqlite":
+		return DriverSqlite3
+	default:
+		return name
 	}
 }
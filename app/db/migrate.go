@@ -0,0 +1,261 @@
+package db
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// The SQLite migrations are in migrations, those of the other databases
+// in a directory named after the driver. Every migration must exist for
+// all databases with the same version.
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+func migrationDir(driver string) string {
+	if driver == DriverSqlite3 {
+		return "migrations"
+	}
+	return "migrations/" + driver
+}
+
+// versionTable is the table goose keeps the applied migrations in, so
+// Migrate and the goose targets of the Makefile can be used on the same
+// database.
+const versionTable = "goose_db_version"
+
+// Migration is a goose SQL migration.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Migrations returns the embedded migrations of the driver ordered by
+// version.
+func Migrations(driver string) ([]Migration, error) {
+	files, err := fs.Glob(migrationFS, migrationDir(driver)+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s: %w", name, err)
+		}
+		b, err := migrationFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMigration(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", name, err)
+		}
+		m.Version, m.Name = version, name
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parseMigration splits a migration into its statements. Like goose, a
+// statement ends with a line ending in a semicolon unless it's enclosed
+// in StatementBegin and StatementEnd annotations.
+func parseMigration(text string) (Migration, error) {
+	var (
+		m       Migration
+		section *[]string
+		stmt    strings.Builder
+		block   bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &m.Up
+			case "Down":
+				section = &m.Down
+			case "StatementBegin":
+				block = true
+			case "StatementEnd":
+				block = false
+				*section = append(*section, stmt.String())
+				stmt.Reset()
+			}
+			continue
+		}
+		if section == nil || len(trimmed) == 0 || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if !block && strings.HasSuffix(trimmed, ";") {
+			*section = append(*section, stmt.String())
+			stmt.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return m, err
+	}
+	if len(strings.TrimSpace(stmt.String())) > 0 {
+		return m, fmt.Errorf("statement without a terminating semicolon: %s", stmt.String())
+	}
+	if section == nil {
+		return m, fmt.Errorf("missing -- +goose Up annotation")
+	}
+	return m, nil
+}
+
+// createVersionTable creates the version table like goose does.
+var createVersionTable = map[string]string{
+	DriverSqlite3: `create table ` + versionTable + `(
+		id integer primary key autoincrement,
+		version_id integer not null,
+		is_applied integer not null,
+		tstamp timestamp default (datetime('now'))
+	)`,
+	DriverPostgres: `create table ` + versionTable + `(
+		id integer primary key generated by default as identity,
+		version_id bigint not null,
+		is_applied boolean not null,
+		tstamp timestamp not null default now()
+	)`,
+	DriverMysql: `create table ` + versionTable + `(
+		id serial not null,
+		version_id bigint not null,
+		is_applied boolean not null,
+		tstamp timestamp null default now(),
+		primary key(id)
+	)`,
+}
+
+type gooseVersion struct {
+	ID        int64 `gorm:"primaryKey"`
+	VersionID int64
+	IsApplied bool
+}
+
+func (gooseVersion) TableName() string {
+	return versionTable
+}
+
+// Migrate applies the embedded migrations the database is missing and
+// returns how many were applied.
+func Migrate(instance *gorm.DB) (int, error) {
+	driver := Driver(instance)
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(instance, driver)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		err := instance.Transaction(func(tx *gorm.DB) error {
+			for _, stmt := range m.Up {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Create(&gooseVersion{VersionID: m.Version, IsApplied: true}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %s failed: %w", m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// appliedVersions reads the migrations applied by Migrate or goose. Goose
+// appends a row for every up and down, the last one of a version wins.
+func appliedVersions(instance *gorm.DB, driver string) (map[int64]bool, error) {
+	if !instance.Migrator().HasTable(versionTable) {
+		stmt, ok := createVersionTable[driver]
+		if !ok {
+			return nil, fmt.Errorf("no migrations for driver %s", driver)
+		}
+		if err := instance.Exec(stmt).Error; err != nil {
+			return nil, err
+		}
+		if err := instance.Create(&gooseVersion{VersionID: 0, IsApplied: true}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return readVersions(instance)
+}
+
+// Pending returns the embedded migrations the database is missing,
+// without creating the version table.
+func Pending(instance *gorm.DB) ([]Migration, error) {
+	migrations, err := Migrations(Driver(instance))
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]bool{}
+	if instance.Migrator().HasTable(versionTable) {
+		if applied, err = readVersions(instance); err != nil {
+			return nil, err
+		}
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Version returns the highest migration version applied to the
+// database, zero if it was never migrated.
+func Version(instance *gorm.DB) (int64, error) {
+	if !instance.Migrator().HasTable(versionTable) {
+		return 0, nil
+	}
+	applied, err := readVersions(instance)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v, ok := range applied {
+		if ok && v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+func readVersions(instance *gorm.DB) (map[int64]bool, error) {
+	var versions []gooseVersion
+	if err := instance.Order("id").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v.VersionID] = v.IsApplied
+	}
+	return applied, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"testing"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestParseMigration(t *testing.T) {
+	m, err := parseMigration(`-- +goose Up
+create table a(id integer);
+-- a comment
+create table b(
+	id integer
+);
+-- +goose StatementBegin
+create trigger t after insert on a begin
+	insert into b values (new.id);
+end;
+-- +goose StatementEnd
+
+-- +goose Down
+drop table b;
+drop table a;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Up) != 3 || len(m.Down) != 2 {
+		t.Fatalf("expected 3 up and 2 down statements, got %q and %q", m.Up, m.Down)
+	}
+	if m.Up[2] != "create trigger t after insert on a begin\n\tinsert into b values (new.id);\nend;\n" {
+		t.Fatalf("unexpected statement block %q", m.Up[2])
+	}
+
+	if _, err := parseMigration("-- +goose Up\ncreate table a(id integer)\n"); err == nil {
+		t.Fatal("expected an error for a statement without semicolon")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	instance, err := Open(Config{Driver: "sqlite3", Name: Memory, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := Migrations(DriverSqlite3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := Pending(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(migrations) {
+		t.Fatalf("expected %d pending migrations, got %d", len(migrations), len(pending))
+	}
+
+	n, err := Migrate(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(migrations) {
+		t.Fatalf("expected %d migrations to be applied, got %d", len(migrations), n)
+	}
+	for _, table := range []string{"users", "sessions", "german_words", "prompt_overrides"} {
+		if !instance.Migrator().HasTable(table) {
+			t.Errorf("expected table %s to exist", table)
+		}
+	}
+
+	if n, err := Migrate(instance); err != nil || n != 0 {
+		t.Fatalf("expected nothing to migrate, got %d, %v", n, err)
+	}
+	if pending, err := Pending(instance); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no pending migrations, got %d, %v", len(pending), err)
+	}
+	if v, err := Version(instance); err != nil || v != migrations[len(migrations)-1].Version {
+		t.Fatalf("expected version %d, got %d, %v", migrations[len(migrations)-1].Version, v, err)
+	}
+}
+
+// TestMigrationDrivers checks that every migration exists for all
+// databases.
+func TestMigrationDrivers(t *testing.T) {
+	sqlite, err := Migrations(DriverSqlite3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, driver := range []string{DriverPostgres, DriverMysql} {
+		migrations, err := Migrations(driver)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if len(migrations) != len(sqlite) {
+			t.Fatalf("%s: expected %d migrations, got %d", driver, len(sqlite), len(migrations))
+		}
+		for i, m := range migrations {
+			if m.Name != sqlite[i].Name {
+				t.Errorf("%s: expected migration %s, got %s", driver, sqlite[i].Name, m.Name)
+			}
+			if len(m.Up) == 0 || len(m.Down) == 0 {
+				t.Errorf("%s: migration %s has no up or down statements", driver, m.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists german_words(
+	id integer primary key,
+	example text,
+	german_word text,
+	definition text,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime
+);
+
+-- +goose Down
+drop table if exists german_words;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists api_tokens(
+	id integer primary key,
+	user_id integer not null references users,
+	name text not null,
+	token_hash text unique not null,
+	scopes text not null,
+	last_used_at datetime,
+	expires_at datetime,
+	created_at datetime not null,
+	updated_at datetime not null,
+	deleted_at datetime
+);
+
+-- +goose Down
+drop table if exists api_tokens;
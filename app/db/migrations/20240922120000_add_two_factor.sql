@@ -0,0 +1,18 @@
+-- +goose Up
+alter table users add column totp_secret text not null default '';
+alter table users add column totp_enabled_at datetime;
+
+create table if not exists recovery_codes(
+	id integer primary key,
+	user_id integer not null references users,
+	code_hash text not null,
+	used_at datetime,
+	created_at datetime not null,
+	updated_at datetime not null,
+	deleted_at datetime
+);
+
+-- +goose Down
+drop table if exists recovery_codes;
+alter table users drop column totp_enabled_at;
+alter table users drop column totp_secret;
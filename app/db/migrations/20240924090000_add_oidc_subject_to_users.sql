@@ -0,0 +1,7 @@
+-- +goose Up
+alter table users add column oidc_subject text not null default '';
+create index if not exists users_oidc_subject_idx on users(oidc_subject);
+
+-- +goose Down
+drop index if exists users_oidc_subject_idx;
+alter table users drop column oidc_subject;
@@ -0,0 +1,5 @@
+-- +goose Up
+alter table users add column deletion_requested_at datetime;
+
+-- +goose Down
+alter table users drop column deletion_requested_at;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists ai_usages(
+	id integer primary key,
+	user_id integer not null default 0,
+	operation text not null,
+	model text not null,
+	prompt_tokens integer not null default 0,
+	completion_tokens integer not null default 0,
+	total_tokens integer not null default 0,
+	cost real not null default 0,
+	created_at datetime not null
+);
+create index if not exists ai_usages_user_id_created_at_idx on ai_usages(user_id, created_at);
+
+-- +goose Down
+drop table if exists ai_usages;
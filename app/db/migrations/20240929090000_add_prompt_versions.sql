@@ -0,0 +1,16 @@
+-- +goose Up
+alter table german_words add column prompt_version text not null default '';
+
+create table if not exists prompt_overrides(
+	id integer primary key,
+	name text not null,
+	language text not null,
+	template text not null,
+	created_by integer not null,
+	created_at datetime not null
+);
+create index if not exists prompt_overrides_name_language_idx on prompt_overrides(name, language);
+
+-- +goose Down
+drop table if exists prompt_overrides;
+alter table german_words drop column prompt_version;
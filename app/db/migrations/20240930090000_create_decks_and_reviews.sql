@@ -0,0 +1,42 @@
+-- +goose Up
+create table if not exists decks(
+	id integer primary key,
+	user_id integer not null references users(id),
+	name text not null,
+	description text not null default '',
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime
+);
+create index if not exists decks_user_id_idx on decks(user_id);
+
+alter table german_words add column deck_id integer references decks(id);
+alter table german_words add column due_at datetime;
+alter table german_words add column interval_days integer not null default 0;
+alter table german_words add column ease real not null default 0;
+alter table german_words add column repetitions integer not null default 0;
+create index if not exists german_words_deck_id_idx on german_words(deck_id);
+create index if not exists german_words_user_id_due_at_idx on german_words(user_id, due_at);
+
+create table if not exists reviews(
+	id integer primary key,
+	user_id integer not null references users(id),
+	entry_id integer not null references german_words(id),
+	grade text not null,
+	interval_days integer not null,
+	ease real not null,
+	reviewed_at datetime not null
+);
+create index if not exists reviews_user_id_reviewed_at_idx on reviews(user_id, reviewed_at);
+create index if not exists reviews_entry_id_idx on reviews(entry_id);
+
+-- +goose Down
+drop table if exists reviews;
+drop index if exists german_words_user_id_due_at_idx;
+drop index if exists german_words_deck_id_idx;
+alter table german_words drop column repetitions;
+alter table german_words drop column ease;
+alter table german_words drop column interval_days;
+alter table german_words drop column due_at;
+alter table german_words drop column deck_id;
+drop table if exists decks;
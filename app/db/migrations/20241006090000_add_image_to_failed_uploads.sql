@@ -0,0 +1,5 @@
+-- +goose Up
+alter table failed_uploads add column image blob;
+
+-- +goose Down
+alter table failed_uploads drop column image;
@@ -0,0 +1,15 @@
+-- +goose Up
+create table if not exists users(
+	id bigint unsigned auto_increment primary key,
+	email varchar(255) unique not null,
+	password_hash text not null,
+	first_name text not null,
+	last_name text not null,
+	email_verified_at datetime(3),
+	created_at datetime(3) not null,
+	updated_at datetime(3) not null,
+	deleted_at datetime(3)
+);
+
+-- +goose Down
+drop table if exists users;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists sessions(
+	id bigint unsigned auto_increment primary key,
+	token varchar(255) not null,
+	user_id bigint unsigned not null,
+	ip_address text,
+	user_agent text,
+	expires_at datetime(3) not null,
+	created_at datetime(3) not null,
+	updated_at datetime(3) not null,
+	deleted_at datetime(3),
+	foreign key (user_id) references users(id)
+);
+
+-- +goose Down
+drop table if exists sessions;
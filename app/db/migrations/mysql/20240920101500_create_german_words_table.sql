@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists german_words(
+	id bigint unsigned auto_increment primary key,
+	example text,
+	german_word text,
+	definition text,
+	created_at datetime(3),
+	updated_at datetime(3),
+	deleted_at datetime(3)
+);
+
+-- +goose Down
+drop table if exists german_words;
@@ -0,0 +1,9 @@
+-- +goose Up
+alter table german_words add column user_id bigint unsigned;
+alter table german_words add constraint german_words_user_id_fk foreign key (user_id) references users(id);
+create index idx_german_words_user_id on german_words(user_id);
+
+-- +goose Down
+alter table german_words drop foreign key german_words_user_id_fk;
+drop index idx_german_words_user_id on german_words;
+alter table german_words drop column user_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+create table if not exists api_tokens(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null,
+	name text not null,
+	token_hash varchar(255) unique not null,
+	scopes text not null,
+	last_used_at datetime(3),
+	expires_at datetime(3),
+	created_at datetime(3) not null,
+	updated_at datetime(3) not null,
+	deleted_at datetime(3),
+	foreign key (user_id) references users(id)
+);
+
+-- +goose Down
+drop table if exists api_tokens;
@@ -0,0 +1,19 @@
+-- +goose Up
+alter table users add column totp_secret varchar(255) not null default '';
+alter table users add column totp_enabled_at datetime(3);
+
+create table if not exists recovery_codes(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null,
+	code_hash text not null,
+	used_at datetime(3),
+	created_at datetime(3) not null,
+	updated_at datetime(3) not null,
+	deleted_at datetime(3),
+	foreign key (user_id) references users(id)
+);
+
+-- +goose Down
+drop table if exists recovery_codes;
+alter table users drop column totp_enabled_at;
+alter table users drop column totp_secret;
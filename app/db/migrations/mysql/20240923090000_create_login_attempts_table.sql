@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists login_attempts(
+	id bigint unsigned auto_increment primary key,
+	email varchar(255) not null,
+	ip_address text,
+	user_agent text,
+	reason text not null,
+	created_at datetime(3) not null
+);
+create index idx_login_attempts_email on login_attempts(email);
+
+-- +goose Down
+drop table if exists login_attempts;
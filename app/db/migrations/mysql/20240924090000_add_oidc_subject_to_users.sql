@@ -0,0 +1,7 @@
+-- +goose Up
+alter table users add column oidc_subject varchar(255) not null default '';
+create index users_oidc_subject_idx on users(oidc_subject);
+
+-- +goose Down
+drop index users_oidc_subject_idx on users;
+alter table users drop column oidc_subject;
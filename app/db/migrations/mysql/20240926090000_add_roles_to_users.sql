@@ -0,0 +1,9 @@
+-- +goose Up
+alter table users add column role varchar(32) not null default 'user';
+alter table users add column disabled_at datetime(3);
+alter table sessions add column impersonator_id bigint unsigned not null default 0;
+
+-- +goose Down
+alter table sessions drop column impersonator_id;
+alter table users drop column disabled_at;
+alter table users drop column role;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists ai_usages(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null default 0,
+	operation varchar(255) not null,
+	model varchar(255) not null,
+	prompt_tokens integer not null default 0,
+	completion_tokens integer not null default 0,
+	total_tokens integer not null default 0,
+	cost double not null default 0,
+	created_at datetime(3) not null
+);
+create index ai_usages_user_id_created_at_idx on ai_usages(user_id, created_at);
+
+-- +goose Down
+drop table if exists ai_usages;
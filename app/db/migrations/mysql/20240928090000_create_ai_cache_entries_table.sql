@@ -0,0 +1,14 @@
+-- +goose Up
+create table if not exists ai_cache_entries(
+	`key` varchar(64) primary key,
+	provider varchar(255) not null,
+	model varchar(255) not null,
+	prompt_version varchar(255) not null,
+	response longblob not null,
+	created_at datetime(3) not null,
+	expires_at datetime(3) not null
+);
+create index ai_cache_entries_expires_at_idx on ai_cache_entries(expires_at);
+
+-- +goose Down
+drop table if exists ai_cache_entries;
@@ -0,0 +1,16 @@
+-- +goose Up
+alter table german_words add column prompt_version varchar(255) not null default '';
+
+create table if not exists prompt_overrides(
+	id bigint unsigned auto_increment primary key,
+	name varchar(255) not null,
+	language varchar(16) not null,
+	template text not null,
+	created_by bigint unsigned not null,
+	created_at datetime(3) not null
+);
+create index prompt_overrides_name_language_idx on prompt_overrides(name, language);
+
+-- +goose Down
+drop table if exists prompt_overrides;
+alter table german_words drop column prompt_version;
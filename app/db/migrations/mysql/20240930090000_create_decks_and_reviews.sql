@@ -0,0 +1,43 @@
+-- +goose Up
+create table if not exists decks(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null,
+	name varchar(255) not null,
+	description text not null,
+	created_at datetime(3),
+	updated_at datetime(3),
+	deleted_at datetime(3),
+	constraint decks_user_id_fk foreign key (user_id) references users(id)
+);
+
+alter table german_words add column deck_id bigint unsigned;
+alter table german_words add constraint german_words_deck_id_fk foreign key (deck_id) references decks(id);
+alter table german_words add column due_at datetime(3);
+alter table german_words add column interval_days integer not null default 0;
+alter table german_words add column ease double not null default 0;
+alter table german_words add column repetitions integer not null default 0;
+create index german_words_user_id_due_at_idx on german_words(user_id, due_at);
+
+create table if not exists reviews(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null,
+	entry_id bigint unsigned not null,
+	grade varchar(16) not null,
+	interval_days integer not null,
+	ease double not null,
+	reviewed_at datetime(3) not null,
+	constraint reviews_user_id_fk foreign key (user_id) references users(id),
+	constraint reviews_entry_id_fk foreign key (entry_id) references german_words(id)
+);
+create index reviews_user_id_reviewed_at_idx on reviews(user_id, reviewed_at);
+
+-- +goose Down
+drop table if exists reviews;
+drop index german_words_user_id_due_at_idx on german_words;
+alter table german_words drop foreign key german_words_deck_id_fk;
+alter table german_words drop column repetitions;
+alter table german_words drop column ease;
+alter table german_words drop column interval_days;
+alter table german_words drop column due_at;
+alter table german_words drop column deck_id;
+drop table if exists decks;
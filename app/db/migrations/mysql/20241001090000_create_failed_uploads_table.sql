@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists failed_uploads(
+	id bigint unsigned auto_increment primary key,
+	user_id bigint unsigned not null,
+	source varchar(16) not null,
+	language varchar(16) not null default '',
+	image_size integer not null,
+	image_sha256 char(64) not null,
+	error text not null,
+	created_at datetime(3) not null,
+	constraint failed_uploads_user_id_fk foreign key (user_id) references users(id)
+);
+create index failed_uploads_created_at_idx on failed_uploads(created_at);
+
+-- +goose Down
+drop table if exists failed_uploads;
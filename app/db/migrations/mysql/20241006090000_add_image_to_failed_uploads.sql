@@ -0,0 +1,6 @@
+-- +goose Up
+-- Uploads are at most 10 MB, a blob only holds 64 KB.
+alter table failed_uploads add column image mediumblob;
+
+-- +goose Down
+alter table failed_uploads drop column image;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Set on the demo account, only the seeded account is reset by the demo.
+alter table users add column demo_seeded_at datetime(3);
+
+-- +goose Down
+alter table users drop column demo_seeded_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists german_words(
+	id bigserial primary key,
+	example text,
+	german_word text,
+	definition text,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz
+);
+
+-- +goose Down
+drop table if exists german_words;
@@ -0,0 +1,7 @@
+-- +goose Up
+alter table german_words add column user_id bigint references users;
+create index if not exists idx_german_words_user_id on german_words(user_id);
+
+-- +goose Down
+drop index if exists idx_german_words_user_id;
+alter table german_words drop column user_id;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists api_tokens(
+	id bigserial primary key,
+	user_id bigint not null references users,
+	name text not null,
+	token_hash text unique not null,
+	scopes text not null,
+	last_used_at timestamptz,
+	expires_at timestamptz,
+	created_at timestamptz not null,
+	updated_at timestamptz not null,
+	deleted_at timestamptz
+);
+
+-- +goose Down
+drop table if exists api_tokens;
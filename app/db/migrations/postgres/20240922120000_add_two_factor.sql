@@ -0,0 +1,18 @@
+-- +goose Up
+alter table users add column totp_secret text not null default '';
+alter table users add column totp_enabled_at timestamptz;
+
+create table if not exists recovery_codes(
+	id bigserial primary key,
+	user_id bigint not null references users,
+	code_hash text not null,
+	used_at timestamptz,
+	created_at timestamptz not null,
+	updated_at timestamptz not null,
+	deleted_at timestamptz
+);
+
+-- +goose Down
+drop table if exists recovery_codes;
+alter table users drop column totp_enabled_at;
+alter table users drop column totp_secret;
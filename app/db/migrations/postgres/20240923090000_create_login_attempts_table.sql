@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists login_attempts(
+	id bigserial primary key,
+	email text not null,
+	ip_address text,
+	user_agent text,
+	reason text not null,
+	created_at timestamptz not null
+);
+create index if not exists idx_login_attempts_email on login_attempts(email);
+
+-- +goose Down
+drop table if exists login_attempts;
@@ -0,0 +1,9 @@
+-- +goose Up
+alter table users add column role text not null default 'user';
+alter table users add column disabled_at timestamptz;
+alter table sessions add column impersonator_id bigint not null default 0;
+
+-- +goose Down
+alter table sessions drop column impersonator_id;
+alter table users drop column disabled_at;
+alter table users drop column role;
@@ -0,0 +1,14 @@
+-- +goose Up
+create table if not exists ai_cache_entries(
+	key text primary key,
+	provider text not null,
+	model text not null,
+	prompt_version text not null,
+	response bytea not null,
+	created_at timestamptz not null,
+	expires_at timestamptz not null
+);
+create index if not exists ai_cache_entries_expires_at_idx on ai_cache_entries(expires_at);
+
+-- +goose Down
+drop table if exists ai_cache_entries;
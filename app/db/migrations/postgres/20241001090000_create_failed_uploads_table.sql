@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists failed_uploads(
+	id bigserial primary key,
+	user_id bigint not null references users(id),
+	source text not null,
+	language text not null default '',
+	image_size integer not null,
+	image_sha256 text not null,
+	error text not null,
+	created_at timestamptz not null
+);
+create index if not exists failed_uploads_created_at_idx on failed_uploads(created_at);
+create index if not exists failed_uploads_user_id_idx on failed_uploads(user_id);
+
+-- +goose Down
+drop table if exists failed_uploads;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- The time step of the last accepted TOTP code, codes can only be used once.
+alter table users add column totp_last_step bigint not null default 0;
+
+-- +goose Down
+alter table users drop column totp_last_step;
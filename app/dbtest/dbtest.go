@@ -0,0 +1,99 @@
+// Package dbtest provides hermetic databases for tests.
+//
+// By default every test gets an in-memory SQLite database. To run the
+// tests against Postgres or MySQL set TEST_DB_DRIVER and the connection
+// settings TEST_DB_HOST, TEST_DB_PORT, TEST_DB_USER, TEST_DB_PASSWORD
+// and TEST_DB_PARAMS, see make test-postgres and make test-mysql. Every
+// test then gets a new database that is dropped when it ends.
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"smartquiz/app/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// New returns a database with all migrations applied and makes it the
+// instance returned by db.Get until the test ends. Tests using it must
+// not run in parallel.
+func New(t testing.TB) *gorm.DB {
+	t.Helper()
+	config := configFromEnv()
+	if config.Driver != db.DriverSqlite3 {
+		config.Name = createDatabase(t, config)
+	}
+	instance, err := db.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Migrate(instance); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Set(nil)
+		if sqlDB, err := instance.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	db.Set(instance)
+	return instance
+}
+
+func configFromEnv() db.Config {
+	config := db.Config{
+		Driver:   os.Getenv("TEST_DB_DRIVER"),
+		Name:     db.Memory,
+		Host:     os.Getenv("TEST_DB_HOST"),
+		Port:     os.Getenv("TEST_DB_PORT"),
+		User:     os.Getenv("TEST_DB_USER"),
+		Password: os.Getenv("TEST_DB_PASSWORD"),
+		Params:   os.Getenv("TEST_DB_PARAMS"),
+		Logger:   logger.Default.LogMode(logger.Silent),
+	}
+	if len(config.Driver) == 0 {
+		config.Driver = db.DriverSqlite3
+	}
+	return config
+}
+
+// createDatabase creates a database with a random name on the server
+// and drops it at the end of the test.
+func createDatabase(t testing.TB, config db.Config) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	name := "dbtest_" + hex.EncodeToString(b)
+
+	// Connect to the database that always exists to create the test
+	// database.
+	config.Name = "postgres"
+	if config.Driver == db.DriverMysql {
+		config.Name = ""
+	}
+	server, err := db.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Exec("create database " + name).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Cleanups run last in first out, so this runs after the test
+	// database was closed.
+	t.Cleanup(func() {
+		if err := server.Exec("drop database " + name).Error; err != nil {
+			t.Errorf("failed to drop the test database %s: %v", name, err)
+		}
+		if sqlDB, err := server.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return name
+}
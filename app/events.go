@@ -1,10 +1,10 @@
 package app
 
 import (
+	"context"
 	"smartquiz/app/events"
+	"smartquiz/pkg/tracing"
 	"smartquiz/plugins/auth"
-
-	"github.com/anthdm/superkit/event"
 )
 
 // Events are functions that are handled in separate goroutines.
@@ -14,8 +14,18 @@ import (
 // - sending notifications (Slack, Telegram, Discord)
 // - analytics..
 
-// Register your events here.
+// Register your events here. The handlers run in the trace of the
+// request that emitted the event with tracing.Emit, and DrainEvents
+// waits for them.
 func RegisterEvents() {
-	event.Subscribe(auth.UserSignupEvent, events.OnUserSignup)
-	event.Subscribe(auth.ResendVerificationEvent, events.OnResendVerificationToken)
+	tracing.Subscribe(auth.UserSignupEvent, events.OnUserSignup)
+	tracing.Subscribe(auth.ResendVerificationEvent, events.OnResendVerificationToken)
+	tracing.Subscribe(auth.PasswordResetEvent, events.OnPasswordReset)
+}
+
+// DrainEvents waits until the emitted events were handled, or the
+// context is done. No events must be emitted anymore, i.e. the HTTP
+// server has to be shut down first.
+func DrainEvents(ctx context.Context) error {
+	return tracing.Wait(ctx)
 }
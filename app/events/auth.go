@@ -1,10 +1,14 @@
 package events
 
 import (
+	"smartquiz/app/conf"
+	"smartquiz/app/mail"
 	"smartquiz/plugins/auth"
 	"context"
-	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
 )
 
 // Event handlers
@@ -13,8 +17,7 @@ func OnUserSignup(ctx context.Context, event any) {
 	if !ok {
 		return
 	}
-	b, _ := json.MarshalIndent(userWithToken, "   ", "    ")
-	fmt.Println(string(b))
+	sendVerificationMail(ctx, userWithToken)
 }
 
 func OnResendVerificationToken(ctx context.Context, event any) {
@@ -22,6 +25,53 @@ func OnResendVerificationToken(ctx context.Context, event any) {
 	if !ok {
 		return
 	}
-	b, _ := json.MarshalIndent(userWithToken, "   ", "    ")
-	fmt.Println(string(b))
+	sendVerificationMail(ctx, userWithToken)
+}
+
+func OnPasswordReset(ctx context.Context, event any) {
+	userWithToken, ok := event.(auth.UserWithResetToken)
+	if !ok {
+		return
+	}
+	user := userWithToken.User
+	msg, err := mail.PasswordResetMessage(user.Email, mail.LinkData{
+		Name:      user.FirstName,
+		URL:       mail.URL("/password/reset?token=" + url.QueryEscape(userWithToken.Token)),
+		ExpiresIn: hours(conf.Get().Auth.PasswordResetExpiryInHours),
+	})
+	send(ctx, msg, err)
+}
+
+func sendVerificationMail(ctx context.Context, userWithToken auth.UserWithVerificationToken) {
+	user := userWithToken.User
+	msg, err := mail.VerificationMessage(user.Email, mail.LinkData{
+		Name:      user.FirstName,
+		URL:       mail.URL("/email/verify?token=" + url.QueryEscape(userWithToken.Token)),
+		ExpiresIn: hours(conf.Get().Auth.EmailVerificationExpiryInHours),
+	})
+	send(ctx, msg, err)
+}
+
+// Event handlers run in the background, failures can only be logged.
+func send(ctx context.Context, msg mail.Message, err error) {
+	if err != nil {
+		slog.Error("failed to render mail", "subject", msg.Subject, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	mailer, err := mail.Get()
+	if err == nil {
+		err = mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		slog.Error("failed to send mail", "to", msg.To, "subject", msg.Subject, "err", err)
+	}
+}
+
+func hours(n int) string {
+	if n == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", n)
 }
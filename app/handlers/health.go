@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+// HandleHealthz tells the orchestrator that the process is alive.
+func HandleHealthz(kit *kit.Kit) error {
+	return kit.Text(http.StatusOK, "ok")
+}
+
+// readiness is the response of HandleReadyz, it maps every check to "ok"
+// or the reason it failed.
+type readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// HandleReadyz tells the load balancer whether the app can serve
+// requests: the database is reachable, its migrations are current and
+// the AI provider is configured.
+func HandleReadyz(kit *kit.Kit) error {
+	ctx, cancel := context.WithTimeout(kit.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := map[string]func() error{
+		"database":   func() error { return checkDatabase(ctx) },
+		"migrations": checkMigrations,
+		"ai":         func() error { return ai.CheckProvider(ai.GetProvider()) },
+	}
+	res := readiness{Ready: true, Checks: make(map[string]string, len(checks))}
+	for name, check := range checks {
+		if err := check(); err != nil {
+			res.Ready = false
+			res.Checks[name] = err.Error()
+			continue
+		}
+		res.Checks[name] = "ok"
+	}
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	return kit.JSON(status, res)
+}
+
+func checkDatabase(ctx context.Context) error {
+	sqlDB, err := db.Get().DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func checkMigrations() error {
+	pending, err := db.Pending(db.Get())
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations pending, the first is %s", len(pending), pending[0].Name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/dbtest"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+type fakeProvider struct {
+	err error
+}
+
+func (fakeProvider) Name() string {
+	return "fake"
+}
+
+func (fakeProvider) Vision(context.Context, ai.VisionRequest) (ai.VisionResponse, error) {
+	return ai.VisionResponse{}, errors.New("not implemented")
+}
+
+func (p fakeProvider) Check() error {
+	return p.err
+}
+
+func TestHandleReadyz(t *testing.T) {
+	dbtest.New(t)
+	t.Cleanup(func() { ai.SetProvider(nil) })
+
+	tests := []struct {
+		provider ai.Provider
+		status   int
+	}{
+		{fakeProvider{}, http.StatusOK},
+		{fakeProvider{err: errors.New("no API key configured")}, http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		ai.SetProvider(test.provider)
+		rec := httptest.NewRecorder()
+		kit.Handler(HandleReadyz)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != test.status {
+			t.Errorf("expected status %d, got %d: %s", test.status, rec.Code, rec.Body)
+		}
+		var res readiness
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Checks["database"] != "ok" || res.Checks["migrations"] != "ok" {
+			t.Errorf("expected the database checks to pass, got %v", res.Checks)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"smartquiz/app/db"
 	"smartquiz/app/types"
@@ -13,19 +13,17 @@ import (
 
 func HandleTrackIndex(kit *kit.Kit) error {
 	var germanWords []types.GermanWord
-	// var germanWord types.GermanWord
-	err := db.Get().Find(&germanWords).Error
+	err := db.Get().WithContext(kit.Request.Context()).
+		Where("user_id = ?", currentUserID(kit)).
+		Find(&germanWords).Error
 	if err != nil {
-		fmt.Println("Unable to query glossary from database", err)
+		slog.ErrorContext(kit.Request.Context(), "unable to query glossary from database", "err", err)
 		http.Error(kit.Response, "Unable to query glossary from database", http.StatusInternalServerError)
 		germanWords = append(germanWords, types.GermanWord{
 			Example:    "a",
 			GermanWord: "b",
 			Definition: "c",
 		})
-	} else {
-		fmt.Println("words: ", germanWords)
-		// germanWords = append(germanWords, germanWord)
 	}
 
 	// Render the template with the fetched data
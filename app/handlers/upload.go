@@ -1,63 +1,98 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"smartquiz/app/ai"
 	"smartquiz/app/db"
+	"smartquiz/app/metrics"
 	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
 
 	"github.com/anthdm/superkit/kit"
 	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
 )
 
 func HandleUpload(kit *kit.Kit) error {
+	outcome := "error"
+	defer func() {
+		metrics.Uploads.WithLabelValues("web", outcome).Inc()
+	}()
+
 	// Parse the multipart form in the request
-	fmt.Println("entering handle for upload")
 	err := kit.Request.ParseMultipartForm(10 << 20) // limit your max input length!
 	if err != nil {
-		fmt.Println("error when parsed", err)
+		outcome = "invalid"
+		slog.WarnContext(kit.Request.Context(), "failed to parse the upload", "err", err)
 		http.Error(kit.Response, err.Error(), http.StatusInternalServerError)
 		return err
 	}
-	fmt.Println("succesfully parsed file")
 
 	// FormFile returns the first file for the given key 'picture'
 	file, _, err := kit.Request.FormFile("file")
 	if err != nil {
-		fmt.Println("error when fromfile", err)
+		slog.WarnContext(kit.Request.Context(), "upload without a file", "err", err)
+		outcome = "invalid"
 		http.Error(kit.Response, err.Error(), http.StatusInternalServerError)
 		return err
 	}
 	defer file.Close()
 
-	fmt.Println("succesfully fromfile")
-
 	// Read file contents into a byte slice
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
-		fmt.Println("error when reading file bytes", err)
+		slog.ErrorContext(kit.Request.Context(), "failed to read the upload", "err", err)
 		http.Error(kit.Response, err.Error(), http.StatusInternalServerError)
 		return err
 	}
 
 	// Now you have the file as bytes in fileBytes
-	fmt.Println("successfully read file into bytes")
-	visionRes := ai.ReadPicture(fileBytes)
+	userID := currentUserID(kit)
+	if err := ai.CheckQuota(kit.Request.Context(), userID); err != nil {
+		var quotaErr *ai.QuotaError
+		if errors.As(err, &quotaErr) {
+			outcome = "quota_exceeded"
+			return kit.Text(http.StatusTooManyRequests, quotaErr.Error()+", see /usage")
+		}
+		return err
+	}
+	ctx := kit.Request.Context()
+	if kit.Request.FormValue("regenerate") == "true" {
+		ctx = ai.WithoutCache(ctx)
+	}
+	pictureReq := ai.PictureRequest{
+		UserID:   userID,
+		Image:    fileBytes,
+		Language: kit.Request.FormValue("language"),
+	}
+	visionRes, err := ai.ReadPicture(ctx, pictureReq)
+	if err != nil {
+		ai.RecordFailedUpload(ctx, "web", pictureReq, err)
+		return err
+	}
 	germanWord := types.GermanWord{
-		Example:    visionRes.Example,
-		GermanWord: visionRes.Glossary,
-		Definition: visionRes.Definition,
+		UserID:        userID,
+		Example:       visionRes.Example,
+		GermanWord:    visionRes.Glossary,
+		Definition:    visionRes.Definition,
+		PromptVersion: visionRes.PromptVersion,
 	}
-	err = db.Get().Save(&germanWord).Error
+	err = db.Get().WithContext(kit.Request.Context()).Save(&germanWord).Error
 	if err != nil {
-		fmt.Println("error when saving glossary to database", err)
 		return err
 	}
+	outcome = "success"
 	return kit.Redirect(http.StatusSeeOther, "/track")
 }
 
+// currentUserID returns the ID of the logged in user. The routes using
+// it require a login, anonymous uploads would share a single quota.
+func currentUserID(kit *kit.Kit) uint {
+	return kit.Auth().(auth.Auth).UserID
+}
+
 //	// Create a new file in the current working directory
 //	dst, err := os.Create(handler.Filename)
 //	if err != nil {
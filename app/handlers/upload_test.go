@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/conf"
+	"smartquiz/app/dbtest"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+func TestHandleUploadQuotaExceeded(t *testing.T) {
+	database := dbtest.New(t)
+	ai.SetProvider(fakeProvider{})
+	t.Cleanup(func() { ai.SetProvider(nil) })
+	previous := conf.Get()
+	c := *previous
+	c.AI.DailyTokenQuota = 100
+	conf.Set(&c)
+	t.Cleanup(func() { conf.Set(previous) })
+
+	user := auth.User{Email: "alice@example.com", PasswordHash: "x"}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&types.AIUsage{UserID: user.ID, Operation: "vision", TotalTokens: 100}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "word.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("not really a picture"))
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r = r.WithContext(context.WithValue(r.Context(), kit.AuthKey{}, auth.Auth{UserID: user.ID, Email: user.Email, LoggedIn: true}))
+	rec := httptest.NewRecorder()
+	kit.Handler(HandleUpload)(rec, r)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "daily AI quota") {
+		t.Errorf("expected the quota in the response, got %q", rec.Body)
+	}
+	var count int64
+	database.Model(&types.GermanWord{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no entry, got %d", count)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"time"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+	"smartquiz/app/views/usage"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+// HandleUsageIndex shows the AI usage of the current user and how much
+// of their quotas is left.
+func HandleUsageIndex(kit *kit.Kit) error {
+	userID := currentUserID(kit)
+	now := time.Now()
+
+	today, err := ai.UsageSince(kit.Request.Context(), userID, ai.StartOfDay(now))
+	if err != nil {
+		return err
+	}
+	month, err := ai.UsageSince(kit.Request.Context(), userID, ai.StartOfMonth(now))
+	if err != nil {
+		return err
+	}
+	var recent []types.AIUsage
+	err = db.Get().Where("user_id = ?", userID).Order("created_at desc").Limit(20).Find(&recent).Error
+	if err != nil {
+		return err
+	}
+
+	return kit.Render(usage.Index(usage.IndexData{
+		Today:  today,
+		Month:  month,
+		Quota:  ai.QuotaFromConfig(),
+		Recent: recent,
+	}))
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"smartquiz/app/ai"
+	"smartquiz/app/backup"
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/app/seed"
+	"smartquiz/plugins/auth"
+	"time"
+)
+
+// Jobs are long running functions that do periodic work in the
+// background, like cleaning up expired data.
+
+// Start your jobs here.
+func StartJobs(ctx context.Context) {
+	c := conf.Get()
+	go auth.CleanupExpiredSessions(ctx, minutes(c.Auth.SessionCleanupIntervalInMinutes))
+	go auth.PurgeDeletedAccounts(ctx, minutes(c.Auth.AccountPurgeIntervalInMinutes))
+	go ai.CleanupCache(ctx, minutes(c.AI.CacheCleanupIntervalInMinutes))
+	if len(c.Backup.Dir) > 0 {
+		go backup.Schedule(ctx, db.Get(), c.Backup.Dir, c.Backup.Interval, c.Backup.Keep)
+	}
+	if c.Demo.Enabled {
+		go seed.ResetDaily(ctx, c.Demo.ResetAt, c.Demo.Email, c.Demo.Password)
+	}
+}
+
+func minutes(n int) time.Duration {
+	return time.Minute * time.Duration(n)
+}
@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"smartquiz/app/conf"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverOutbox = "outbox"
+)
+
+// Message is a single email with a plain text and an HTML body.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Driver string
+	From   string
+	// SMTP settings, only used by the smtp driver.
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// OutboxDir is where the outbox driver writes its .eml files.
+	// Messages are only logged when it is empty.
+	OutboxDir string
+}
+
+// ConfigFromConf returns the mail settings of the app configuration.
+func ConfigFromConf(c conf.Mail) Config {
+	return Config{
+		Driver:    c.Driver,
+		From:      c.From,
+		Host:      c.SMTPHost,
+		Port:      c.SMTPPort,
+		Username:  c.SMTPUsername,
+		Password:  c.SMTPPassword,
+		OutboxDir: c.OutboxDir,
+	}
+}
+
+// New returns the Mailer for the configured driver.
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case DriverSMTP:
+		if len(config.Host) == 0 {
+			return nil, fmt.Errorf("mail driver smtp requires SMTP_HOST")
+		}
+		return &SMTPMailer{
+			Addr:     config.Host + ":" + config.Port,
+			Host:     config.Host,
+			Username: config.Username,
+			Password: config.Password,
+			From:     config.From,
+		}, nil
+	case DriverOutbox:
+		return &OutboxMailer{Dir: config.OutboxDir, From: config.From}, nil
+	default:
+		return nil, fmt.Errorf("invalid mail driver: %q", config.Driver)
+	}
+}
+
+var mailer Mailer
+
+// Init creates the Mailer returned by Get. It is called at startup, so
+// an invalid configuration stops the app before the first mail.
+func Init(config Config) error {
+	m, err := New(config)
+	if err != nil {
+		return err
+	}
+	Set(m)
+	return nil
+}
+
+// Set replaces the Mailer returned by Get, e.g. in tests.
+func Set(m Mailer) {
+	mailer = m
+}
+
+// Get returns the Mailer set with Init or Set.
+func Get() (Mailer, error) {
+	if mailer == nil {
+		return nil, errors.New("mail: not initialized, call mail.Init first")
+	}
+	return mailer, nil
+}
+
+// build encodes the message as a multipart/alternative MIME message.
+func build(from string, msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, part := range parts {
+		if len(part.content) == 0 {
+			continue
+		}
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write([]byte(normalizeNewlines(part.content))); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + w.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// OutboxMailer is the development driver. Instead of delivering messages
+// it writes them as .eml files into Dir. When Dir is empty it only logs
+// the recipient and the subject, the body may contain login links.
+type OutboxMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	if len(m.Dir) == 0 {
+		slog.Info("outbox mail, set MAIL_OUTBOX_DIR to keep the messages", "to", msg.To, "subject", msg.Subject)
+		return nil
+	}
+
+	b, err := build(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml",
+		time.Now().Format("20060102-150405.000000000"),
+		unsafeFileChars.ReplaceAllString(msg.To, "_"),
+	)
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return err
+	}
+	slog.Info("outbox mail", "to", msg.To, "subject", msg.Subject, "file", path)
+	return nil
+}
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOutboxDoesNotLogBody(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	msg := Message{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Text:    "Open https://smartquiz.example.com/password/reset?token=secret-token",
+	}
+	if err := (&OutboxMailer{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "user@example.com") {
+		t.Errorf("expected the recipient to be logged, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "secret-token") {
+		t.Errorf("expected the body not to be logged, got %s", logs.String())
+	}
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single SMTP session and records the
+// envelope and the message data it receives.
+type fakeSMTPServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: ln, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost fake smtp")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(b)
+			tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	mailer, err := New(Config{
+		Driver: DriverSMTP,
+		From:   "smartquiz <no-reply@example.com>",
+		Host:   host,
+		Port:   port,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := VerificationMessage("anna@example.com", LinkData{
+		Name:      "Anna",
+		URL:       "http://localhost/email/verify?token=abc&x=1",
+		ExpiresIn: "1 hour",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	if server.from != "no-reply@example.com" {
+		t.Errorf("expected envelope sender no-reply@example.com, got %q", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "anna@example.com" {
+		t.Errorf("expected recipient anna@example.com, got %v", server.to)
+	}
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(server.data)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("Subject"); got != "Verify your email address" {
+		t.Errorf("unexpected subject %q", got)
+	}
+	if !strings.HasPrefix(header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected multipart/alternative, got %q", header.Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"text/plain",
+		"text/html",
+		"http://localhost/email/verify?token=abc&x=1",
+		"http://localhost/email/verify?token=abc&amp;x=1",
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+}
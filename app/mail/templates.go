@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"smartquiz/app/conf"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// LinkData is used by the mails that ask the user to follow a link.
+type LinkData struct {
+	Name      string
+	URL       string
+	ExpiresIn string
+}
+
+type ReminderData struct {
+	Name      string
+	WordCount int64
+	URL       string
+}
+
+// URL returns the absolute URL of the given path, based on APP_URL.
+func URL(path string) string {
+	return strings.TrimRight(conf.Get().AppURL, "/") + path
+}
+
+func VerificationMessage(to string, data LinkData) (Message, error) {
+	return render("verification", to, "Verify your email address", data)
+}
+
+func PasswordResetMessage(to string, data LinkData) (Message, error) {
+	return render("password_reset", to, "Reset your password", data)
+}
+
+func ReminderMessage(to string, data ReminderData) (Message, error) {
+	return render("reminder", to, "Time for a quick quiz", data)
+}
+
+func render(name, to, subject string, data any) (Message, error) {
+	msg := Message{To: to, Subject: subject}
+
+	text, err := texttemplate.ParseFS(templatesFS, "templates/"+name+".txt")
+	if err != nil {
+		return msg, err
+	}
+	var b bytes.Buffer
+	if err := text.Execute(&b, data); err != nil {
+		return msg, err
+	}
+	msg.Text = b.String()
+
+	html, err := htmltemplate.ParseFS(templatesFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return msg, err
+	}
+	b.Reset()
+	if err := html.ExecuteTemplate(&b, "layout.html", data); err != nil {
+		return msg, err
+	}
+	msg.HTML = b.String()
+
+	return msg, nil
+}
@@ -0,0 +1,116 @@
+// Package metrics serves the Prometheus metrics of the app on /metrics.
+// Packages define the metrics they record themselves, like the AI
+// metrics in app/ai, this package holds the ones shared by several
+// packages and the collectors of the database.
+package metrics
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/plugins/auth"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Namespace prefixes the names of all metrics of the app.
+const Namespace = "smartquiz"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by chi route pattern.",
+		// Uploads wait for the AI provider, hence the long tail.
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	// Uploads counts the picture uploads of the web app and the API by
+	// outcome: success, invalid, quota_exceeded or error.
+	Uploads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "uploads_total",
+		Help:      "Picture uploads by source and outcome.",
+	}, []string{"source", "outcome"})
+)
+
+// Middleware records the count and latency of the requests. Routes are
+// labeled with their chi pattern, e.g. /api/v1/entries/{id}, to keep
+// the number of series bounded.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		defer func() {
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.RoutePattern()) > 0 {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// RegisterDB registers the connection pool stats of the database and
+// the number of active sessions.
+func RegisterDB(instance *gorm.DB) error {
+	sqlDB, err := instance.DB()
+	if err != nil {
+		return err
+	}
+	if err := prometheus.Register(collectors.NewDBStatsCollector(sqlDB, "main")); err != nil {
+		return err
+	}
+	return prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "active_sessions",
+		Help:      "Login sessions that haven't expired.",
+	}, func() float64 {
+		var n int64
+		err := instance.Model(&auth.Session{}).Where("expires_at > ?", time.Now()).Count(&n).Error
+		if err != nil {
+			slog.Error("failed to count the active sessions", "err", err)
+			return math.NaN()
+		}
+		return float64(n)
+	}))
+}
+
+// Handler serves the metrics. If METRICS_TOKEN is set the scraper has
+// to send it as a bearer token.
+func Handler() http.Handler {
+	metrics := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := conf.Get().MetricsToken; len(token) > 0 {
+			sent, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/dbtest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T, token string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, req)
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/entries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/entries/42", nil))
+
+	_, body := scrape(t, "")
+	expected := `smartquiz_http_requests_total{method="GET",route="/entries/{id}",status="404"} 1`
+	if !strings.Contains(body, expected) {
+		t.Errorf("expected %s in\n%s", expected, body)
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	c := *conf.Get()
+	c.MetricsToken = "scrape-token"
+	conf.Set(&c)
+	t.Cleanup(func() { conf.Set(nil) })
+
+	if code, _ := scrape(t, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", code)
+	}
+	if code, _ := scrape(t, "scrape-token"); code != http.StatusOK {
+		t.Errorf("expected 200 with token, got %d", code)
+	}
+}
+
+func TestRegisterDB(t *testing.T) {
+	if err := RegisterDB(dbtest.New(t)); err != nil {
+		t.Fatal(err)
+	}
+	_, body := scrape(t, "")
+	for _, name := range []string{"smartquiz_active_sessions 0", `go_sql_open_connections{db_name="main"}`} {
+		if !strings.Contains(body, name) {
+			t.Errorf("expected %s in the metrics", name)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package review
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var reviews = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "smartquiz",
+	Name:      "reviews_total",
+	Help:      "Reviews submitted by grade, again, hard, good or easy.",
+}, []string{"grade"})
@@ -0,0 +1,167 @@
+// Package review schedules the entries of a user for spaced repetition
+// with a variant of the SM-2 algorithm. Every quiz, in the browser, the
+// API or the terminal, submits its answers through Submit.
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"gorm.io/gorm"
+)
+
+// Grade is how well the user remembered an entry.
+type Grade string
+
+const (
+	// Again means the entry was forgotten.
+	Again Grade = "again"
+	// Hard means the entry was remembered with difficulty, e.g. with a
+	// typo or without its article.
+	Hard Grade = "hard"
+	Good Grade = "good"
+	// Easy means the entry was remembered without any effort.
+	Easy Grade = "easy"
+)
+
+// Grades are all grades, worst first.
+var Grades = []Grade{Again, Hard, Good, Easy}
+
+// ParseGrade returns the grade named s.
+func ParseGrade(s string) (Grade, error) {
+	for _, g := range Grades {
+		if string(g) == s {
+			return g, nil
+		}
+	}
+	return "", fmt.Errorf("invalid grade %q, must be one of again, hard, good or easy", s)
+}
+
+const (
+	// DefaultEase is the ease of entries that were never reviewed.
+	DefaultEase = 2.5
+	// MinEase keeps the intervals of hard entries from shrinking forever.
+	MinEase = 1.3
+	// relearn is when a forgotten entry is due again.
+	relearn = 10 * time.Minute
+)
+
+// ErrNotFound is returned by Submit for entries that don't exist or
+// belong to another user.
+var ErrNotFound = errors.New("entry not found")
+
+// State is the scheduling state of an entry.
+type State struct {
+	Repetitions  int
+	IntervalDays int
+	Ease         float64
+	DueAt        *time.Time
+}
+
+// StateOf returns the scheduling state of the entry.
+func StateOf(word types.GermanWord) State {
+	return State{
+		Repetitions:  word.Repetitions,
+		IntervalDays: word.IntervalDays,
+		Ease:         word.Ease,
+		DueAt:        word.DueAt,
+	}
+}
+
+// Next returns the state after answering with the grade at now. A
+// forgotten entry starts over and is due again in a few minutes, the
+// interval of the others grows with the ease.
+func Next(s State, grade Grade, now time.Time) State {
+	ease := s.Ease
+	if ease == 0 {
+		ease = DefaultEase
+	}
+	next := State{Repetitions: s.Repetitions + 1, Ease: ease}
+	switch grade {
+	case Again:
+		due := now.Add(relearn)
+		return State{Ease: math.Max(MinEase, ease-0.2), DueAt: &due}
+	case Hard:
+		next.Ease = math.Max(MinEase, ease-0.15)
+		next.IntervalDays = max(1, int(math.Round(float64(s.IntervalDays)*1.2)))
+	case Good:
+		next.IntervalDays = goodInterval(s, ease)
+	case Easy:
+		next.Ease = ease + 0.15
+		next.IntervalDays = max(4, int(math.Round(float64(goodInterval(s, ease))*1.3)))
+	}
+	due := now.AddDate(0, 0, next.IntervalDays)
+	next.DueAt = &due
+	return next
+}
+
+func goodInterval(s State, ease float64) int {
+	switch s.Repetitions {
+	case 0:
+		return 1
+	case 1:
+		return 6
+	}
+	return max(s.IntervalDays+1, int(math.Round(float64(s.IntervalDays)*ease)))
+}
+
+// Submit records the answer of the user to the entry and schedules its
+// next review. Reviews are counted by grade on /metrics.
+func Submit(ctx context.Context, userID, entryID uint, grade Grade, now time.Time) (types.GermanWord, types.Review, error) {
+	var (
+		word   types.GermanWord
+		review types.Review
+	)
+	err := db.Get().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&word, "id = ? AND user_id = ?", entryID, userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		next := Next(StateOf(word), grade, now)
+		word.Repetitions = next.Repetitions
+		word.IntervalDays = next.IntervalDays
+		word.Ease = next.Ease
+		word.DueAt = next.DueAt
+		err = tx.Model(&word).Select("repetitions", "interval_days", "ease", "due_at").Updates(&word).Error
+		if err != nil {
+			return err
+		}
+		review = types.Review{
+			UserID:       userID,
+			EntryID:      word.ID,
+			Grade:        string(grade),
+			IntervalDays: next.IntervalDays,
+			Ease:         next.Ease,
+			ReviewedAt:   now,
+		}
+		return tx.Create(&review).Error
+	})
+	if err == nil {
+		reviews.WithLabelValues(string(grade)).Inc()
+	}
+	return word, review, err
+}
+
+// Due returns a query for the entries of the user that are due at now,
+// those of a deck if deckID isn't zero. Use Order to review the most
+// overdue entries first and new entries last.
+func Due(ctx context.Context, userID, deckID uint, now time.Time) *gorm.DB {
+	query := db.Get().WithContext(ctx).Model(&types.GermanWord{}).
+		Where("user_id = ? AND (due_at IS NULL OR due_at <= ?)", userID, now)
+	if deckID != 0 {
+		query = query.Where("deck_id = ?", deckID)
+	}
+	return query
+}
+
+// Order is the order of the due queue.
+const Order = "CASE WHEN due_at IS NULL THEN 1 ELSE 0 END, due_at, id"
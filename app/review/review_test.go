@@ -0,0 +1,100 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNext(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		state    State
+		grade    Grade
+		interval int
+		ease     float64
+	}{
+		{"new good", State{}, Good, 1, DefaultEase},
+		{"new easy", State{}, Easy, 4, DefaultEase + 0.15},
+		{"new hard", State{}, Hard, 1, DefaultEase - 0.15},
+		{"second good", State{Repetitions: 1, IntervalDays: 1, Ease: 2.5}, Good, 6, 2.5},
+		{"third good", State{Repetitions: 2, IntervalDays: 6, Ease: 2.5}, Good, 15, 2.5},
+		{"hard", State{Repetitions: 3, IntervalDays: 15, Ease: 2.5}, Hard, 18, 2.35},
+		{"min ease", State{Repetitions: 3, IntervalDays: 15, Ease: MinEase}, Hard, 18, MinEase},
+	}
+	for _, test := range tests {
+		next := Next(test.state, test.grade, now)
+		if next.IntervalDays != test.interval || next.Ease != test.ease {
+			t.Errorf("%s: expected an interval of %d days and an ease of %v, got %d and %v",
+				test.name, test.interval, test.ease, next.IntervalDays, next.Ease)
+		}
+		if next.DueAt == nil || !next.DueAt.Equal(now.AddDate(0, 0, test.interval)) {
+			t.Errorf("%s: expected to be due in %d days, got %v", test.name, test.interval, next.DueAt)
+		}
+	}
+
+	next := Next(State{Repetitions: 4, IntervalDays: 30, Ease: 2.5}, Again, now)
+	if next.Repetitions != 0 || next.IntervalDays != 0 || next.Ease != 2.3 || !next.DueAt.Equal(now.Add(relearn)) {
+		t.Errorf("expected a forgotten entry to start over, got %+v", next)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	database := dbtest.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	users := []auth.User{
+		{Email: "anna@example.com", FirstName: "Anna", LastName: "Arndt"},
+		{Email: "ben@example.com", FirstName: "Ben", LastName: "Bauer"},
+	}
+	database.Create(&users)
+	userID := users[0].ID
+	words := []types.GermanWord{
+		{UserID: userID, GermanWord: "das Haus"},
+		{UserID: userID, GermanWord: "der Tisch"},
+		{UserID: users[1].ID, GermanWord: "die Stadt"},
+	}
+	database.Create(&words)
+
+	good := testutil.ToFloat64(reviews.WithLabelValues("good"))
+	word, review, err := Submit(ctx, userID, words[0].ID, Good, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word.Repetitions != 1 || word.DueAt == nil || review.EntryID != word.ID || review.Grade != "good" {
+		t.Errorf("expected the entry to be scheduled and the review recorded, got %+v and %+v", word, review)
+	}
+	if _, _, err := Submit(ctx, userID, words[2].ID, Good, now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the entry of another user not to be found, got %v", err)
+	}
+	if n := testutil.ToFloat64(reviews.WithLabelValues("good")) - good; n != 1 {
+		t.Errorf("expected 1 good review on /metrics, got %v", n)
+	}
+
+	var due []types.GermanWord
+	if err := Due(ctx, userID, 0, now).Order(Order).Find(&due).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ID != words[1].ID {
+		t.Errorf("expected only der Tisch to be due, got %+v", due)
+	}
+	due = nil
+	Due(ctx, userID, 0, now.AddDate(0, 0, 2)).Order(Order).Find(&due)
+	if len(due) != 2 || due[0].ID != words[0].ID {
+		t.Errorf("expected das Haus to be due first the day after tomorrow, got %+v", due)
+	}
+
+	var reviews int64
+	database.Model(&types.Review{}).Count(&reviews)
+	if reviews != 1 {
+		t.Errorf("expected 1 review, got %d", reviews)
+	}
+}
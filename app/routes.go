@@ -2,9 +2,17 @@ package app
 
 import (
 	"log/slog"
+	"smartquiz/app/api"
+	"smartquiz/app/conf"
 	"smartquiz/app/handlers"
+	"smartquiz/app/metrics"
 	"smartquiz/app/views/errors"
+	"smartquiz/pkg/logging"
+	"smartquiz/pkg/ratelimit"
+	"smartquiz/pkg/tracing"
+	"smartquiz/plugins/admin"
 	"smartquiz/plugins/auth"
+	"time"
 
 	"github.com/anthdm/superkit/kit"
 	"github.com/anthdm/superkit/kit/middleware"
@@ -14,7 +22,16 @@ import (
 
 // Define your global middleware
 func InitializeMiddleware(router *chi.Mux) {
-	router.Use(chimiddleware.Logger)
+	// Only trust the X-Forwarded-For and X-Real-IP headers when running
+	// behind a reverse proxy, otherwise clients could spoof their IP
+	// address and get around the rate limits.
+	if conf.Get().TrustProxyHeaders {
+		router.Use(chimiddleware.RealIP)
+	}
+	router.Use(chimiddleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(logging.Middleware)
+	router.Use(metrics.Middleware)
 	router.Use(chimiddleware.Recoverer)
 	router.Use(middleware.WithRequest)
 }
@@ -30,22 +47,32 @@ func InitializeRoutes(router *chi.Mux) {
 	//      AuthFunc: YourAuthHandler,
 	//      RedirectURL: "/login",
 	//  }
+	// Probes of the orchestrator and load balancer.
+	router.Get("/healthz", kit.Handler(handlers.HandleHealthz))
+	router.Get("/readyz", kit.Handler(handlers.HandleReadyz))
+	router.Handle("/metrics", metrics.Handler())
+
 	auth.InitializeRoutes(router)
+	admin.InitializeRoutes(router)
 	authConfig := kit.AuthenticationConfig{
 		AuthFunc:    auth.AuthenticateUser,
 		RedirectURL: "/login",
 	}
 
+	// Every upload costs us a call to the AI provider, so the web and the
+	// API uploads share one limit per user (or IP address).
+	uploadLimiter := ratelimit.New(conf.Get().UploadRateLimitPerHour, time.Hour)
+
+	// JSON API under /api/v1, see app/api.
+	api.InitializeRoutes(router, uploadLimiter)
+
 	// Routes that "might" have an authenticated user
 	router.Group(func(app chi.Router) {
 		app.Use(kit.WithAuthentication(authConfig, false)) // strict set to false
 
 		// Routes
 		app.Get("/", kit.Handler(handlers.HandleLandingIndex))
-		app.Get("/track", kit.Handler(handlers.HandleTrackIndex))
 		app.Get("/quiz", kit.Handler(handlers.HandleQuizIndex))
-		app.Post("/upload", kit.Handler(handlers.HandleUpload))
-		app.Get("/uploadpage", kit.Handler(handlers.HandleUploadIndex))
 	})
 
 	// Authenticated routes
@@ -57,6 +84,13 @@ func InitializeRoutes(router *chi.Mux) {
 		app.Use(kit.WithAuthentication(authConfig, true)) // strict set to true
 
 		// Routes
+		app.Get("/track", kit.Handler(handlers.HandleTrackIndex))
+		app.Get("/usage", kit.Handler(handlers.HandleUsageIndex))
+		// Uploads need an owner for the entry and a user to count the AI
+		// usage against.
+		app.Get("/uploadpage", kit.Handler(handlers.HandleUploadIndex))
+		app.With(uploadLimiter.Middleware(auth.RateLimitKey, nil)).
+			Post("/upload", kit.Handler(handlers.HandleUpload))
 	})
 }
 
@@ -68,6 +102,6 @@ func NotFoundHandler(kit *kit.Kit) error {
 
 // ErrorHandler that will be called on errors return from application handlers.
 func ErrorHandler(kit *kit.Kit, err error) {
-	slog.Error("internal server error", "err", err.Error(), "path", kit.Request.URL.Path)
+	slog.ErrorContext(kit.Request.Context(), "internal server error", "err", err, "path", kit.Request.URL.Path)
 	kit.Render(errors.Error500())
 }
@@ -0,0 +1,51 @@
+package seed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"smartquiz/app/ai"
+)
+
+// Provider answers the vision requests with a word of the demo
+// vocabulary instead of asking a model. Demo mode uses it, so uploads
+// work without an API key and never reach the real provider. The same
+// image always gets the same word.
+type Provider struct{}
+
+func (Provider) Name() string {
+	return "demo"
+}
+
+func (Provider) Vision(ctx context.Context, req ai.VisionRequest) (ai.VisionResponse, error) {
+	words := allWords()
+	sum := sha256.Sum256(req.Image)
+	word := words[binary.BigEndian.Uint64(sum[:8])%uint64(len(words))]
+	content, err := json.Marshal(ai.Json{
+		Glossary:   word.Word,
+		Definition: word.Definition,
+		Example:    word.Example,
+	})
+	if err != nil {
+		return ai.VisionResponse{}, err
+	}
+	return ai.VisionResponse{
+		Id:      fmt.Sprintf("demo-%x", sum[:6]),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: string(content)}}},
+	}, nil
+}
+
+func allWords() []Word {
+	var words []Word
+	for _, level := range Vocabulary {
+		words = append(words, level.Words...)
+	}
+	return words
+}
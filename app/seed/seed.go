@@ -0,0 +1,206 @@
+// Package seed fills a database with a demo account and runs the demo
+// mode, see conf.Demo.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/review"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"gorm.io/gorm"
+)
+
+// history is the time span the creation of the demo entries is spread
+// over, the A1 words are the oldest.
+const history = 8 * 7 * 24 * time.Hour
+
+// lastSession is how long ago the demo learner reviewed the last time,
+// the entries due since then are waiting in the queue.
+const lastSession = 2 * 24 * time.Hour
+
+// grades are the answers of the demo learner in turn, mostly good.
+var grades = []review.Grade{review.Good, review.Good, review.Hard, review.Good, review.Easy, review.Good, review.Again, review.Good}
+
+// ErrNotDemoAccount is returned by Demo when the email address belongs
+// to an account that wasn't seeded as the demo account.
+var ErrNotDemoAccount = errors.New("the account was not seeded as the demo account")
+
+// Demo creates the demo user with the demo vocabulary, a deck per level
+// and the history of its reviews. Entries have no pictures, so none are
+// seeded. The demo account seeded before is purged first, together with
+// everything the visitors of the demo added to it. Any other account
+// with the email address is left alone and ErrNotDemoAccount returned.
+// The data of the app must have been registered with
+// auth.RegisterAccountData.
+func Demo(email, password string) (auth.User, error) {
+	var user auth.User
+	now := time.Now()
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		var existing auth.User
+		if err := tx.Unscoped().Where("email = ?", email).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			if !existing.DemoSeededAt.Valid {
+				return fmt.Errorf("%s: %w", email, ErrNotDemoAccount)
+			}
+			if err := auth.PurgeAccountTx(tx, existing); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		user, err = auth.CreateUserTx(tx, auth.SignupFormValues{
+			Email:           email,
+			FirstName:       "Demo",
+			LastName:        "User",
+			Password:        password,
+			PasswordConfirm: password,
+		}, auth.RoleUser)
+		if err != nil {
+			return err
+		}
+		user.EmailVerifiedAt = sql.NullTime{Time: now, Valid: true}
+		user.DemoSeededAt = user.EmailVerifiedAt
+		err = tx.Model(&user).Select("email_verified_at", "demo_seeded_at").Updates(&user).Error
+		if err != nil {
+			return err
+		}
+
+		decks := Decks(user.ID)
+		if err := tx.Create(&decks).Error; err != nil {
+			return err
+		}
+		entries := Entries(user.ID, now)
+		i := 0
+		for d, level := range Vocabulary {
+			for range level.Words {
+				entries[i].DeckID = &decks[d].ID
+				i++
+			}
+		}
+		if err := tx.CreateInBatches(entries, 100).Error; err != nil {
+			return err
+		}
+		reviews := Reviews(entries, now)
+		for i := range entries {
+			if entries[i].DueAt == nil {
+				continue
+			}
+			err := tx.Model(&entries[i]).Select("repetitions", "interval_days", "ease", "due_at").Updates(&entries[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.CreateInBatches(reviews, 100).Error
+	})
+	return user, err
+}
+
+// Decks returns a deck of the user for every level of the vocabulary.
+func Decks(userID uint) []types.Deck {
+	decks := make([]types.Deck, len(Vocabulary))
+	for i, level := range Vocabulary {
+		decks[i] = types.Deck{
+			UserID:      userID,
+			Name:        level.Name,
+			Description: "Common words of the level " + level.Name + ".",
+		}
+	}
+	return decks
+}
+
+// Entries returns the demo vocabulary as entries of the user, created
+// in the weeks before now.
+func Entries(userID uint, now time.Time) []types.GermanWord {
+	words := allWords()
+	step := history / time.Duration(len(words))
+	entries := make([]types.GermanWord, len(words))
+	for i, w := range words {
+		created := now.Add(-history + time.Duration(i)*step).Truncate(time.Minute)
+		entries[i] = types.GermanWord{
+			UserID:     userID,
+			GermanWord: w.Word,
+			Definition: w.Definition,
+			Example:    w.Example,
+		}
+		entries[i].CreatedAt = created
+		entries[i].UpdatedAt = created
+	}
+	return entries
+}
+
+// Reviews answers the entries like a learner who reviewed every entry
+// once it was due, from the day after its creation until lastSession
+// before now. It sets the scheduling state of the entries and returns
+// the reviews, the entries must have been created. The entries of the
+// last days were never reviewed.
+func Reviews(entries []types.GermanWord, now time.Time) []types.Review {
+	end := now.Add(-lastSession)
+	var reviews []types.Review
+	for i := range entries {
+		entry := &entries[i]
+		at := entry.CreatedAt.Add(24 * time.Hour)
+		for n := 0; !at.After(end); n++ {
+			grade := grades[(i+3*n)%len(grades)]
+			next := review.Next(review.StateOf(*entry), grade, at)
+			entry.Repetitions = next.Repetitions
+			entry.IntervalDays = next.IntervalDays
+			entry.Ease = next.Ease
+			entry.DueAt = next.DueAt
+			reviews = append(reviews, types.Review{
+				UserID:       entry.UserID,
+				EntryID:      entry.ID,
+				Grade:        string(grade),
+				IntervalDays: next.IntervalDays,
+				Ease:         next.Ease,
+				ReviewedAt:   at,
+			})
+			at = *next.DueAt
+		}
+	}
+	return reviews
+}
+
+// ResetDaily resets the demo account every day at the time of day at,
+// e.g. "03:00" in UTC, until the context is cancelled.
+func ResetDaily(ctx context.Context, at, email, password string) {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		slog.Error("invalid reset time of the demo account", "at", at, "err", err)
+		return
+	}
+	offset := time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+	for {
+		timer := time.NewTimer(time.Until(nextReset(time.Now(), offset)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := Demo(email, password); err != nil {
+			slog.Error("failed to reset the demo account", "err", err)
+			continue
+		}
+		slog.Info("reset the demo account")
+	}
+}
+
+// nextReset returns the next time after now that is offset past
+// midnight UTC.
+func nextReset(now time.Time, offset time.Duration) time.Time {
+	next := now.UTC().Truncate(24 * time.Hour).Add(offset)
+	if !next.After(now) {
+		next = next.Add(24 * time.Hour)
+	}
+	return next
+}
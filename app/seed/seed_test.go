@@ -0,0 +1,162 @@
+package seed_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"smartquiz/app"
+	"smartquiz/app/ai"
+	"smartquiz/app/dbtest"
+	"smartquiz/app/review"
+	"smartquiz/app/seed"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+)
+
+func init() {
+	app.RegisterAccountData()
+}
+
+func TestDemo(t *testing.T) {
+	database := dbtest.New(t)
+	user, err := seed.Demo("demo@example.com", "Demo!quiz1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerifiedAt.Valid || !user.DemoSeededAt.Valid {
+		t.Error("expected the demo user to be verified and marked as the demo account")
+	}
+	// A visitor adds an entry and logs in.
+	database.Create(&types.GermanWord{UserID: user.ID, GermanWord: "der Besuch"})
+	database.Create(&auth.Session{UserID: user.ID, Token: "visitor", ExpiresAt: time.Now().Add(time.Hour)})
+
+	user, err = seed.Demo("demo@example.com", "Demo!quiz1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries, visitors, sessions, users int64
+	database.Model(&types.GermanWord{}).Where("user_id = ?", user.ID).Count(&entries)
+	database.Model(&types.GermanWord{}).Where("german_word = ?", "der Besuch").Count(&visitors)
+	if want := int64(len(seed.Entries(user.ID, time.Now()))); entries != want || visitors != 0 {
+		t.Errorf("expected the %d demo entries only, got %d and %d visitor entries", want, entries, visitors)
+	}
+	database.Model(&auth.Session{}).Count(&sessions)
+	if sessions != 0 {
+		t.Error("expected the sessions of the demo user to be deleted")
+	}
+	database.Unscoped().Model(&auth.User{}).Count(&users)
+	if users != 1 {
+		t.Errorf("expected 1 user, got %d", users)
+	}
+
+	var decks, reviews, due, scheduled int64
+	database.Model(&types.Deck{}).Where("user_id = ?", user.ID).Count(&decks)
+	if decks != int64(len(seed.Vocabulary)) {
+		t.Errorf("expected a deck per level, got %d", decks)
+	}
+	database.Model(&types.GermanWord{}).Where("user_id = ? AND deck_id IS NULL", user.ID).Count(&entries)
+	if entries != 0 {
+		t.Errorf("expected every entry in a deck, got %d without", entries)
+	}
+	database.Model(&types.Review{}).Where("user_id = ?", user.ID).Count(&reviews)
+	review.Due(context.Background(), user.ID, 0, time.Now()).Where("due_at IS NOT NULL").Count(&due)
+	database.Model(&types.GermanWord{}).Where("user_id = ? AND due_at > ?", user.ID, time.Now()).Count(&scheduled)
+	if reviews == 0 || due == 0 || scheduled == 0 {
+		t.Errorf("expected a review history with due and scheduled entries, got %d reviews, %d due and %d scheduled", reviews, due, scheduled)
+	}
+}
+
+func TestDemoOtherAccount(t *testing.T) {
+	database := dbtest.New(t)
+	user := auth.User{Email: "demo@example.com", PasswordHash: "x"}
+	database.Create(&user)
+	database.Create(&types.GermanWord{UserID: user.ID, GermanWord: "das Haus"})
+
+	if _, err := seed.Demo("demo@example.com", "Demo!quiz1"); !errors.Is(err, seed.ErrNotDemoAccount) {
+		t.Fatalf("got error %v, want ErrNotDemoAccount", err)
+	}
+	var users, entries int64
+	database.Model(&auth.User{}).Where("id = ?", user.ID).Count(&users)
+	database.Model(&types.GermanWord{}).Count(&entries)
+	if users != 1 || entries != 1 {
+		t.Errorf("expected the account to be kept, got %d users and %d entries", users, entries)
+	}
+}
+
+func TestDemoRollback(t *testing.T) {
+	database := dbtest.New(t)
+	user, err := seed.Demo("demo@example.com", "Demo!quiz1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before int64
+	database.Model(&types.GermanWord{}).Where("user_id = ?", user.ID).Count(&before)
+
+	// The password is rejected after the account was purged.
+	if _, err := seed.Demo("demo@example.com", "short"); err == nil {
+		t.Fatal("expected an invalid password to be rejected")
+	}
+	var users, entries int64
+	database.Model(&auth.User{}).Where("id = ?", user.ID).Count(&users)
+	database.Model(&types.GermanWord{}).Where("user_id = ?", user.ID).Count(&entries)
+	if users != 1 || entries != before {
+		t.Errorf("expected the demo account to be kept, got %d users and %d of %d entries", users, entries, before)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	entries := seed.Entries(1, now)
+	for i := range entries {
+		entries[i].ID = uint(i + 1)
+	}
+	reviews := seed.Reviews(entries, now)
+	grades := map[string]int{}
+	for _, r := range reviews {
+		grades[r.Grade]++
+		if !r.ReviewedAt.Before(now.AddDate(0, 0, -2)) || r.EntryID == 0 {
+			t.Fatalf("unexpected review %+v", r)
+		}
+	}
+	if len(grades) != len(review.Grades) {
+		t.Errorf("expected reviews of every grade, got %v", grades)
+	}
+	if first := entries[0]; first.Repetitions == 0 || first.IntervalDays < 7 {
+		t.Errorf("expected the oldest entry to be well known, got %d repetitions and an interval of %d days", first.Repetitions, first.IntervalDays)
+	}
+	if last := entries[len(entries)-1]; last.DueAt != nil {
+		t.Errorf("expected the newest entry to be new, due at %v", last.DueAt)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	entries := seed.Entries(1, now)
+	first, last := entries[0], entries[len(entries)-1]
+	if first.GermanWord != "das Haus" || !first.CreatedAt.Before(now.AddDate(0, 0, -50)) {
+		t.Errorf("expected the A1 words weeks ago, got %s at %s", first.GermanWord, first.CreatedAt)
+	}
+	if !last.CreatedAt.Before(now) || last.CreatedAt.Before(now.AddDate(0, 0, -7)) {
+		t.Errorf("expected the last word within the last week, got %s", last.CreatedAt)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	dbtest.New(t)
+	ai.SetProvider(seed.Provider{})
+	t.Cleanup(func() { ai.SetProvider(nil) })
+
+	res, err := ai.ReadPicture(context.Background(), ai.PictureRequest{UserID: 1, Image: []byte("picture")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Glossary) == 0 || len(res.Definition) == 0 || len(res.Example) == 0 {
+		t.Errorf("expected a complete demo word, got %+v", res)
+	}
+	again, err := ai.ReadPicture(context.Background(), ai.PictureRequest{UserID: 1, Image: []byte("picture")})
+	if err != nil || again.Glossary != res.Glossary {
+		t.Errorf("expected the same word for the same picture, got %q and %q, %v", res.Glossary, again.Glossary, err)
+	}
+}
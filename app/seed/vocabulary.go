@@ -0,0 +1,75 @@
+package seed
+
+// Word is an entry of the demo vocabulary.
+type Word struct {
+	// Word with its article, nouns in the nominative singular.
+	Word       string
+	Definition string
+	Example    string
+}
+
+// Level is a list of words of a CEFR level.
+type Level struct {
+	Name  string
+	Words []Word
+}
+
+// Vocabulary are common words of the levels A1 to B2, easiest first.
+var Vocabulary = []Level{
+	{Name: "A1", Words: []Word{
+		{"das Haus", "Ein Gebäude, in dem Menschen wohnen.", "Wir wohnen in einem kleinen Haus am Stadtrand."},
+		{"der Tisch", "Ein Möbelstück mit einer flachen Platte auf Beinen.", "Das Essen steht schon auf dem Tisch."},
+		{"die Stadt", "Ein großer Ort, in dem viele Menschen leben.", "Berlin ist die größte Stadt Deutschlands."},
+		{"das Brot", "Ein gebackenes Lebensmittel aus Mehl, Wasser und Hefe.", "Zum Frühstück esse ich Brot mit Käse."},
+		{"der Bahnhof", "Ein Ort, an dem Züge halten und abfahren.", "Der Zug kommt um acht Uhr am Bahnhof an."},
+		{"die Woche", "Ein Zeitraum von sieben Tagen.", "Nächste Woche habe ich Urlaub."},
+		{"das Kind", "Ein junger Mensch, der noch nicht erwachsen ist.", "Das Kind spielt im Garten."},
+		{"der Freund", "Eine Person, die man mag und der man vertraut.", "Am Wochenende treffe ich meinen Freund."},
+		{"die Arbeit", "Eine Tätigkeit, mit der man Geld verdient.", "Ich fahre jeden Tag mit dem Fahrrad zur Arbeit."},
+		{"das Wetter", "Der Zustand der Luft, zum Beispiel Sonne, Regen oder Wind.", "Das Wetter ist heute sehr schön."},
+		{"kaufen", "Etwas gegen Geld bekommen.", "Ich muss noch Milch kaufen."},
+		{"schnell", "Mit hoher Geschwindigkeit.", "Der Hund läuft sehr schnell."},
+	}},
+	{Name: "A2", Words: []Word{
+		{"die Erfahrung", "Wissen, das man durch Erlebnisse gewonnen hat.", "Sie hat viel Erfahrung in ihrem Beruf."},
+		{"der Termin", "Eine feste Zeit für ein Treffen oder eine Verabredung.", "Ich habe morgen einen Termin beim Arzt."},
+		{"die Rechnung", "Ein Papier, auf dem steht, wie viel man bezahlen muss.", "Können wir bitte die Rechnung haben?"},
+		{"das Gepäck", "Die Koffer und Taschen, die man auf eine Reise mitnimmt.", "Unser Gepäck ist leider nicht angekommen."},
+		{"der Unterschied", "Das, worin sich zwei Dinge nicht gleichen.", "Ich sehe keinen Unterschied zwischen den beiden Farben."},
+		{"die Gesundheit", "Der Zustand, in dem man nicht krank ist.", "Sport ist gut für die Gesundheit."},
+		{"das Angebot", "Etwas, das man zu einem bestimmten Preis kaufen kann.", "Im Supermarkt gibt es diese Woche ein gutes Angebot."},
+		{"der Nachbar", "Eine Person, die nebenan wohnt.", "Unser Nachbar gießt im Urlaub die Blumen."},
+		{"die Erlaubnis", "Das Recht, etwas tun zu dürfen.", "Du brauchst die Erlaubnis deiner Eltern."},
+		{"sich erinnern", "Etwas Vergangenes wieder im Kopf haben.", "Ich erinnere mich gern an unseren Urlaub."},
+		{"pünktlich", "Genau zur vereinbarten Zeit.", "Der Bus kommt heute ausnahmsweise pünktlich."},
+		{"bestellen", "Sagen, dass man etwas haben möchte, das geliefert wird.", "Wir haben eine Pizza bestellt."},
+	}},
+	{Name: "B1", Words: []Word{
+		{"die Voraussetzung", "Etwas, das vorhanden sein muss, damit etwas anderes möglich ist.", "Gute Deutschkenntnisse sind eine Voraussetzung für die Stelle."},
+		{"der Zusammenhang", "Die Beziehung zwischen verschiedenen Dingen oder Ereignissen.", "Ich verstehe den Zusammenhang zwischen den beiden Fragen nicht."},
+		{"die Umwelt", "Die Natur und die Lebensbedingungen, die den Menschen umgeben.", "Wir sollten mehr für den Schutz der Umwelt tun."},
+		{"das Verhalten", "Die Art, wie sich jemand benimmt.", "Sein Verhalten in der Besprechung war unhöflich."},
+		{"der Vorschlag", "Eine Idee, die man anderen zur Entscheidung anbietet.", "Hast du einen besseren Vorschlag?"},
+		{"die Bewerbung", "Die Unterlagen, mit denen man sich um eine Stelle bemüht.", "Ich habe meine Bewerbung gestern abgeschickt."},
+		{"das Gerücht", "Eine Nachricht, von der man nicht weiß, ob sie stimmt.", "Im Büro geht ein Gerücht um."},
+		{"der Eindruck", "Die Meinung, die man von jemandem oder etwas bekommt.", "Sie hat beim Vorstellungsgespräch einen guten Eindruck gemacht."},
+		{"die Verantwortung", "Die Pflicht, für etwas zu sorgen und dafür einzustehen.", "Als Teamleiter trägt er die Verantwortung für das Projekt."},
+		{"sich beschweren", "Sagen, dass man mit etwas unzufrieden ist.", "Die Gäste haben sich über den Lärm beschwert."},
+		{"vorsichtig", "So, dass man Gefahren oder Fehler vermeidet.", "Fahr bitte vorsichtig, die Straßen sind glatt."},
+		{"abhängen", "Von etwas bestimmt werden.", "Ob wir grillen, hängt vom Wetter ab."},
+	}},
+	{Name: "B2", Words: []Word{
+		{"die Angelegenheit", "Eine Sache, um die man sich kümmern muss.", "Das ist eine private Angelegenheit."},
+		{"der Aufwand", "Die Mühe, Zeit oder das Geld, die man für etwas braucht.", "Der Aufwand hat sich am Ende gelohnt."},
+		{"die Nachhaltigkeit", "Eine Wirtschaftsweise, die Ressourcen für künftige Generationen erhält.", "Das Unternehmen setzt auf Nachhaltigkeit in der Produktion."},
+		{"das Bedürfnis", "Der Wunsch oder die Notwendigkeit, etwas zu haben oder zu tun.", "Nach der langen Reise hatte sie das Bedürfnis nach Ruhe."},
+		{"der Widerspruch", "Eine Aussage, die einer anderen entgegensteht.", "In seiner Erklärung gibt es einen Widerspruch."},
+		{"die Auseinandersetzung", "Ein Streit oder eine intensive Beschäftigung mit einem Thema.", "Nach einer heftigen Auseinandersetzung haben sie sich versöhnt."},
+		{"das Vorurteil", "Eine Meinung über jemanden, die man sich ohne Prüfung gebildet hat.", "Wir müssen Vorurteile gegenüber Fremden abbauen."},
+		{"der Maßstab", "Ein Wert, nach dem man etwas beurteilt.", "Ihre Arbeit setzt neue Maßstäbe."},
+		{"die Bravour", "Großes Geschick und Können, mit dem etwas hervorragend gemacht wird.", "Sie meisterte die schwierige Aufgabe mit Bravour."},
+		{"beeinträchtigen", "Die Wirkung oder Qualität von etwas verringern.", "Der Lärm beeinträchtigt meine Konzentration."},
+		{"nachvollziehbar", "So, dass man es verstehen kann.", "Deine Entscheidung ist für mich gut nachvollziehbar."},
+		{"sich auseinandersetzen", "Sich gründlich mit etwas beschäftigen.", "Im Seminar setzen wir uns mit der Geschichte der Stadt auseinander."},
+	}},
+}
@@ -9,9 +9,100 @@ import (
 type GermanWord struct {
 	gorm.Model
 
+	// UserID is the owner of the word. Only words created before entries
+	// had owners have none, NULL in the database.
+	UserID     uint
 	Example    string
 	GermanWord string
 	Definition string
 	created_at time.Time
 	deleted_at time.Time
+	// PromptVersion is the ID of the prompt the entry was generated
+	// with, empty for entries that were added by hand.
+	PromptVersion string
+	// DeckID is the deck of the entry, nil if it is in none.
+	DeckID *uint
+
+	// Scheduling state, see package review. Entries that were never
+	// reviewed have no DueAt and are due right away.
+	DueAt        *time.Time
+	IntervalDays int
+	Ease         float64
+	Repetitions  int
+}
+
+// Deck is a named collection of entries of a user.
+type Deck struct {
+	gorm.Model
+
+	UserID      uint
+	Name        string
+	Description string
+}
+
+// Review is an answer of a user to an entry in a quiz, with the
+// schedule the answer resulted in.
+type Review struct {
+	ID           uint `gorm:"primarykey"`
+	UserID       uint
+	EntryID      uint
+	Grade        string
+	IntervalDays int
+	Ease         float64
+	ReviewedAt   time.Time
+}
+
+// AIUsage records the tokens and the cost of a single call to the AI
+// provider.
+type AIUsage struct {
+	ID               uint `gorm:"primarykey"`
+	UserID           uint
+	Operation        string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// Cost in US dollars, computed from the configured price table at
+	// the time of the call.
+	Cost      float64
+	CreatedAt time.Time
+}
+
+// AICacheEntry is a cached response of the AI provider, see ai.Cache.
+type AICacheEntry struct {
+	Key           string `gorm:"primarykey"`
+	Provider      string
+	Model         string
+	PromptVersion string
+	Response      []byte
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// FailedUpload is an uploaded picture the AI extraction failed for, kept
+// for the admin console and to retry the extraction.
+type FailedUpload struct {
+	ID     uint `gorm:"primarykey"`
+	UserID uint
+	// Source is web or api.
+	Source      string
+	Language    string
+	ImageSize   int
+	ImageSHA256 string `gorm:"column:image_sha256"`
+	// Image is the picture, nil for uploads recorded before the pictures
+	// were kept.
+	Image     []byte `json:"-"`
+	Error     string
+	CreatedAt time.Time
+}
+
+// PromptOverride replaces an embedded prompt template of the AI package
+// without a new release, see ai.GetPrompt.
+type PromptOverride struct {
+	ID        uint `gorm:"primarykey"`
+	Name      string
+	Language  string
+	Template  string
+	CreatedBy uint
+	CreatedAt time.Time
 }
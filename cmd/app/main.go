@@ -1,20 +1,80 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"smartquiz/app"
+	"smartquiz/app/ai"
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/app/mail"
+	"smartquiz/app/metrics"
+	"smartquiz/app/seed"
+	"smartquiz/pkg/logging"
+	"smartquiz/pkg/tracing"
 	"smartquiz/public"
+	"syscall"
+	"time"
 
 	"github.com/anthdm/superkit/kit"
 	"github.com/go-chi/chi/v5"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	kit.Setup()
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "check" {
+		os.Exit(configCheck(args[2:]))
+	}
+
+	c, err := conf.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	setupLogging(c)
+	for _, p := range c.Check() {
+		if p.Warning {
+			slog.Warn("configuration: "+p.Message, "key", p.Key)
+		}
+	}
+	if err := c.Err(); err != nil {
+		log.Fatalf("invalid configuration, run \"config check\" for details:\n%v", err)
+	}
+	conf.Set(c)
+	c.Export()
+	setupKit()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    c.TracingExporter,
+		Endpoint:    c.TracingEndpoint,
+		ServiceName: "smartquiz",
+		SampleRatio: c.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := db.Init(db.ConfigFromConf(c.DB)); err != nil {
+		log.Fatal(err)
+	}
+	if c.DB.AutoMigrate {
+		n, err := db.Migrate(db.Get())
+		if err != nil {
+			log.Fatal(err)
+		}
+		slog.Info("applied migrations", "count", n)
+	}
+	if err := metrics.RegisterDB(db.Get()); err != nil {
+		log.Fatal(err)
+	}
+	if err := mail.Init(mail.ConfigFromConf(c.Mail)); err != nil {
+		log.Fatal(err)
+	}
 	router := chi.NewMux()
 
 	app.InitializeMiddleware(router)
@@ -30,8 +90,22 @@ func main() {
 
 	app.InitializeRoutes(router)
 	app.RegisterEvents()
+	app.RegisterAccountData()
+	if c.Demo.Enabled {
+		// Visitors of the demo get demo words instead of spending the
+		// budget of the AI provider.
+		ai.SetProvider(seed.Provider{})
+		if _, err := seed.Demo(c.Demo.Email, c.Demo.Password); err != nil {
+			log.Fatal(err)
+		}
+		slog.Info("demo mode enabled", "email", c.Demo.Email)
+	}
+	// Stop on SIGINT and SIGTERM. The jobs stop with the context.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	app.StartJobs(ctx)
 
-	listenAddr := os.Getenv("HTTP_LISTEN_ADDR")
+	listenAddr := c.ListenAddr
 	// In development link the full Templ proxy url.
 	url := "http://localhost:7331"
 	if kit.IsProduction() {
@@ -39,9 +113,43 @@ func main() {
 		// url = fmt.Sprintf("http://localhost%s", listenAddr)
 	}
 
-	fmt.Printf("application running in %s at %s\n", kit.Env(), url)
+	slog.Info("application running", "env", kit.Env(), "url", url)
 
-	http.ListenAndServe(listenAddr, router)
+	server := &http.Server{
+		Addr:              listenAddr,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Finish the running requests first, they may still emit events.
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to finish the running requests", "err", err)
+	}
+	if err := app.DrainEvents(shutdownCtx); err != nil {
+		slog.Error("failed to finish the event handlers", "err", err)
+	}
+	if sqlDB, err := db.Get().DB(); err == nil {
+		sqlDB.Close()
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush the traces", "err", err)
+	}
 }
 
 func staticDev() http.Handler {
@@ -59,8 +167,64 @@ func disableCache(next http.Handler) http.Handler {
 	})
 }
 
-func init() {
-	if err := godotenv.Load(); err != nil {
+// setupLogging makes the configured logger the default of slog and of
+// the log package.
+func setupLogging(c *conf.Config) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	slog.SetDefault(logging.New(os.Stderr, logging.Options{
+		Level:   level,
+		JSON:    c.LogFormat == "json",
+		Secrets: c.Secrets(),
+	}))
+}
+
+// configCheck prints the configuration with the secrets redacted and its
+// problems. It returns the exit code, 1 if the configuration is invalid.
+func configCheck(args []string) int {
+	c, err := conf.Load(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	c.Print(os.Stdout)
+	code := 0
+	for _, p := range c.Check() {
+		fmt.Fprintln(os.Stderr, p)
+		if !p.Warning {
+			code = 1
+		}
+	}
+	return code
+}
+
+// setupKit initializes the superkit session store. kit.Setup insists on
+// loading a .env file, which is optional now that the configuration can
+// come from the environment or another file. Without one it runs in an
+// empty directory with an empty .env, the values it needs were exported
+// by conf.
+func setupKit() {
+	if _, err := os.Stat(conf.DefaultFile); err == nil {
+		kit.Setup()
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "smartquiz")
+	if err != nil {
 		log.Fatal(err)
 	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, conf.DefaultFile), nil, 0o600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	kit.Setup()
 }
@@ -1,7 +1,48 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
 
+	"smartquiz/app"
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/app/seed"
+	"smartquiz/app/types"
+)
+
+// Seeds the database of the configuration with the demo account, see
+// DEMO_EMAIL and DEMO_PASSWORD. Running it again resets the account,
+// it refuses to touch any other account with the email address.
 func main() {
-	fmt.Println("there are no seeds.")
+	c, err := conf.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := c.Err(); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	conf.Set(c)
+	if err := db.Init(db.ConfigFromConf(c.DB)); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Migrate(db.Get()); err != nil {
+		log.Fatal(err)
+	}
+	app.RegisterAccountData()
+
+	user, err := seed.Demo(c.Demo.Email, c.Demo.Password)
+	if errors.Is(err, seed.ErrNotDemoAccount) {
+		log.Fatalf("%v, set DEMO_EMAIL to an address without an account", err)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	var entries int64
+	if err := db.Get().Model(&types.GermanWord{}).Where("user_id = ?", user.ID).Count(&entries).Error; err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("seeded the demo user %s (password %s) with %d entries\n", user.Email, c.Demo.Password, entries)
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"smartquiz/app/backup"
+	"smartquiz/app/db"
+)
+
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("o", "", "write the backup to `file`, - for stdout (default smartquiz-<time>.tar.gz)")
+	fs.Parse(args)
+
+	_, instance, err := openDB()
+	if err != nil {
+		return fail(err)
+	}
+	name := *out
+	if len(name) == 0 {
+		name = backup.FileName(time.Now())
+	}
+
+	ctx := context.Background()
+	if name == "-" {
+		if _, err := backup.Create(ctx, instance, os.Stdout); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+	// Write next to the destination and rename, so an interrupted backup
+	// never looks complete.
+	f, err := os.CreateTemp(filepath.Dir(name), ".backup-*")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	manifest, err := backup.Create(ctx, instance, f)
+	if err == nil {
+		err = f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s, schema version %d\n", name, manifest.SchemaVersion)
+	return 0
+}
+
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	verifyOnly := fs.Bool("verify", false, "only verify the backup")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: smartquiz restore [-verify] <file>\n\n"+
+			"Replaces the SQLite database of DB_NAME with the one of the backup.\n"+
+			"Stop the server first.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+	if *verifyOnly {
+		manifest, err := backup.Verify(f)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("%s is valid, created %s, schema version %d\n", fs.Arg(0), manifest.CreatedAt.Format(time.RFC3339), manifest.SchemaVersion)
+		return 0
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		return fail(err)
+	}
+	if c.DB.Driver != db.DriverSqlite3 {
+		return fail(errors.New("restore only supports sqlite3 databases"))
+	}
+	manifest, err := backup.Restore(f, c.DB.Name)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Printf("restored %s from the backup of %s\n", c.DB.Name, manifest.CreatedAt.Format(time.RFC3339))
+
+	// A backup of an older version is brought up to date like on start.
+	if !c.DB.AutoMigrate {
+		return 0
+	}
+	instance, err := db.Open(db.ConfigFromConf(c.DB))
+	if err != nil {
+		return fail(err)
+	}
+	n, err := db.Migrate(instance)
+	if err != nil {
+		return fail(err)
+	}
+	if n > 0 {
+		fmt.Printf("applied %d migrations\n", n)
+	}
+	return 0
+}
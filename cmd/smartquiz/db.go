@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"gorm.io/gorm"
+)
+
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := fs.Bool("status", false, "only list the pending migrations")
+	fs.Parse(args)
+	_, instance, err := openDB()
+	if err != nil {
+		return fail(err)
+	}
+
+	if *status {
+		version, err := db.Version(instance)
+		if err != nil {
+			return fail(err)
+		}
+		pending, err := db.Pending(instance)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("schema version %d, %d pending migrations\n", version, len(pending))
+		for _, m := range pending {
+			fmt.Println("  " + m.Name)
+		}
+		return 0
+	}
+	n, err := db.Migrate(instance)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Printf("applied %d migrations\n", n)
+	return 0
+}
+
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.Parse(args)
+	_, instance, err := openDB()
+	if err != nil {
+		return fail(err)
+	}
+
+	now := time.Now()
+	counts := []struct {
+		label string
+		query *gorm.DB
+	}{
+		{"users", instance.Model(&auth.User{})},
+		{"  verified", instance.Model(&auth.User{}).Where("email_verified_at IS NOT NULL")},
+		{"  disabled", instance.Model(&auth.User{}).Where("disabled_at IS NOT NULL")},
+		{"  admins", instance.Model(&auth.User{}).Where("role = ?", auth.RoleAdmin)},
+		{"active sessions", instance.Model(&auth.Session{}).Where("expires_at > ?", now)},
+		{"api tokens", instance.Model(&auth.APIToken{})},
+		{"entries", instance.Model(&types.GermanWord{})},
+		{"  last 7 days", instance.Model(&types.GermanWord{}).Where("created_at >= ?", now.AddDate(0, 0, -7))},
+		{"ai cache entries", instance.Model(&types.AICacheEntry{}).Where("expires_at > ?", now)},
+	}
+	w := table()
+	for _, c := range counts {
+		var n int64
+		if err := c.query.Count(&n).Error; err != nil {
+			return fail(err)
+		}
+		row(w, c.label, n)
+	}
+
+	var usage ai.UsageSummary
+	err = instance.Model(&types.AIUsage{}).
+		Select("count(*) AS requests, coalesce(sum(total_tokens), 0) AS tokens, coalesce(sum(cost), 0) AS cost").
+		Where("created_at >= ?", ai.StartOfMonth(now)).
+		Scan(&usage).Error
+	if err != nil {
+		return fail(err)
+	}
+	row(w, "ai requests this month", usage.Requests)
+	row(w, "  tokens", usage.Tokens)
+	row(w, "  cost", fmt.Sprintf("$%.2f", usage.Cost))
+	w.Flush()
+	return 0
+}
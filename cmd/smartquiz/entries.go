@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"gorm.io/gorm"
+)
+
+var entryCommands = map[string]command{
+	"list":     {"list the entries of a user or of everyone", runEntryList},
+	"reassign": {"move entries to another user", runEntryReassign},
+}
+
+func runEntryList(args []string) int {
+	fs := flag.NewFlagSet("entries list", flag.ExitOnError)
+	owner := fs.String("user", "", "only list the entries of the user with this `id or email`")
+	query := fs.String("q", "", "only list entries whose word contains `text`")
+	limit := fs.Int("limit", 50, "list at most `n` entries, newest first")
+	fs.Parse(args)
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	tx := db.Get().Order("id desc").Limit(*limit)
+	if len(*owner) > 0 {
+		user, err := findUser(*owner)
+		if err != nil {
+			return fail(err)
+		}
+		tx = tx.Where("user_id = ?", user.ID)
+	}
+	if q := strings.TrimSpace(*query); len(q) > 0 {
+		tx = tx.Where("lower(german_word) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	var words []types.GermanWord
+	if err := tx.Find(&words).Error; err != nil {
+		return fail(err)
+	}
+	w := table("ID", "USER", "WORD", "DEFINITION", "CREATED")
+	for _, word := range words {
+		row(w, word.ID, word.UserID, word.GermanWord, truncate(word.Definition, 40), word.CreatedAt.Format("2006-01-02"))
+	}
+	w.Flush()
+	return 0
+}
+
+func runEntryReassign(args []string) int {
+	fs := flag.NewFlagSet("entries reassign", flag.ExitOnError)
+	from := fs.String("from", "", "move all entries of the user with this `id or email`")
+	to := fs.String("to", "", "the new owner, an `id or email`, required")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: smartquiz entries reassign -to <user> (-from <user> | <entry id>...)")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if len(*to) == 0 || (len(*from) == 0) == (fs.NArg() == 0) {
+		fs.Usage()
+		return 2
+	}
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	target, err := findUser(*to)
+	if err != nil {
+		return fail(err)
+	}
+	tx := db.Get().Model(&types.GermanWord{})
+	if len(*from) > 0 {
+		source, err := findUser(*from)
+		if err != nil {
+			return fail(err)
+		}
+		tx = tx.Where("user_id = ?", source.ID)
+	} else {
+		ids := make([]uint64, fs.NArg())
+		for i, arg := range fs.Args() {
+			if ids[i], err = strconv.ParseUint(arg, 10, 64); err != nil {
+				return fail(fmt.Errorf("invalid entry id %q", arg))
+			}
+		}
+		tx = tx.Where("id IN ?", ids)
+	}
+	var moved []uint
+	if err := tx.Pluck("id", &moved).Error; err != nil {
+		return fail(err)
+	}
+	// The decks belong to the old owner, the review history moves along
+	// with the entries.
+	err = db.Get().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.GermanWord{}).Where("id IN ?", moved).
+			Updates(map[string]any{"user_id": target.ID, "deck_id": nil}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&types.Review{}).Where("entry_id IN ?", moved).Update("user_id", target.ID).Error
+	})
+	if err != nil {
+		return fail(err)
+	}
+	logAction("reassign entries", target)
+	fmt.Printf("moved %d entries to user %d %s\n", len(moved), target.ID, target.Email)
+	return 0
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
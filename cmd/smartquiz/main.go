@@ -0,0 +1,126 @@
+// Command smartquiz administers a smartquiz installation. It reads the
+// same configuration as the server, see app/conf.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+
+	"gorm.io/gorm"
+)
+
+// command is a subcommand. run gets the arguments after the name of
+// the command and returns the exit code.
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"backup":  {"write a backup of the database", runBackup},
+	"restore": {"verify a backup and restore the database from it", runRestore},
+	"users":   {"create, list, verify and disable users, reset passwords", group("users", userCommands)},
+	"entries": {"list entries and move them to another user", group("entries", entryCommands)},
+	"uploads": {"list failed uploads and retry their extraction", group("uploads", uploadCommands)},
+	"migrate": {"apply the pending database migrations", runMigrate},
+	"stats":   {"print statistics of users, entries and AI usage", runStats},
+	"quiz":    {"review entries in the terminal", runQuiz},
+}
+
+func main() {
+	os.Exit(dispatch("smartquiz", commands, os.Args[1:]))
+}
+
+// group returns the run function of a command with subcommands, e.g.
+// "smartquiz users create".
+func group(name string, subs map[string]command) func([]string) int {
+	return func(args []string) int {
+		return dispatch("smartquiz "+name, subs, args)
+	}
+}
+
+func dispatch(name string, cmds map[string]command, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(name, cmds)
+		return 2
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		usage(name, cmds)
+		return 2
+	}
+	return cmd.run(args[1:])
+}
+
+func usage(name string, cmds map[string]command) {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", name)
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", n, cmds[n].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for the flags of a command.\n", name)
+}
+
+// loadConfig reads the configuration from the environment and the
+// configuration file, like the server does.
+func loadConfig() (*conf.Config, error) {
+	c, err := conf.Load(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Err(); err != nil {
+		return nil, fmt.Errorf("invalid configuration, run \"app config check\" for details:\n%w", err)
+	}
+	conf.Set(c)
+	return c, nil
+}
+
+// openDB loads the configuration and opens the database of the server.
+func openDB() (*conf.Config, *gorm.DB, error) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	instance, err := db.Open(db.ConfigFromConf(c.DB))
+	if err != nil {
+		return nil, nil, err
+	}
+	db.Set(instance)
+	return c, instance, nil
+}
+
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	return 1
+}
+
+// table writes aligned columns to stdout, call Flush when done.
+func table(header ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	return w
+}
+
+func row(w io.Writer, values ...any) {
+	for i, v := range values {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, v)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"smartquiz/app/api"
+	"smartquiz/app/review"
+	"smartquiz/app/types"
+
+	"golang.org/x/term"
+)
+
+// card is an entry to review.
+type card struct {
+	ID         uint
+	Word       string
+	Definition string
+	Example    string
+}
+
+// submitFunc saves the answer to a card with the review scheduler.
+type submitFunc func(c card, grade review.Grade) error
+
+func runQuiz(args []string) int {
+	fs := flag.NewFlagSet("quiz", flag.ExitOnError)
+	server := fs.String("server", os.Getenv("SMARTQUIZ_SERVER"), "`URL` of the server, e.g. https://smartquiz.example.com")
+	token := fs.String("token", os.Getenv("SMARTQUIZ_TOKEN"), "personal API `token` with the read and write scopes, for -server")
+	user := fs.String("user", "", "review the entries of the user with this `id or email` in the local database instead")
+	n := fs.Int("n", 20, "review at most `n` cards")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: smartquiz quiz (-server <url> -token <token> | -user <user>) [-n 20]\n\n"+
+			"Reviews the entries that are due in the terminal. Type the word that\n"+
+			"matches the definition, answers that don't match exactly are graded\n"+
+			"by you. The answers schedule the next review of the entries.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *n < 1 {
+		fmt.Fprintln(fs.Output(), "-n must be at least 1")
+		return 2
+	}
+
+	var (
+		cards  []card
+		submit submitFunc
+		err    error
+	)
+	switch {
+	case len(*server) > 0 && len(*user) == 0:
+		if len(*token) == 0 {
+			return fail(errors.New("-server needs an API token, create one on the tokens page"))
+		}
+		cards, submit, err = remoteQuiz(*server, *token, *n)
+	case len(*user) > 0 && len(*server) == 0:
+		cards, submit, err = localQuiz(*user, *n)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if len(cards) == 0 {
+		fmt.Println("Nothing is due for review, upload some pictures or come back later.")
+		return 0
+	}
+
+	// The cards are the most overdue ones, shuffled so their order
+	// doesn't give the answers away.
+	rand.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
+	s := &session{
+		in:     bufio.NewReader(os.Stdin),
+		out:    os.Stdout,
+		clear:  term.IsTerminal(int(os.Stdout.Fd())),
+		submit: submit,
+	}
+	if err := s.review(cards); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// remoteQuiz pulls up to n due entries of the owner of the token from
+// the JSON API and submits the answers to it.
+func remoteQuiz(server, token string, n int) ([]card, submitFunc, error) {
+	base := strings.TrimRight(server, "/") + "/api/v1"
+	due, err := url.Parse(base + "/reviews/due")
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	var cards []card
+	for page := 1; len(cards) < n; page++ {
+		u := *due
+		u.RawQuery = url.Values{"page": {fmt.Sprint(page)}, "per_page": {fmt.Sprint(min(n, 100))}}.Encode()
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+		entries, meta, err := fetchEntries(client, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range entries {
+			cards = append(cards, card{ID: e.ID, Word: e.Word, Definition: e.Definition, Example: e.Example})
+		}
+		if len(entries) == 0 || int64(page*meta.PerPage) >= meta.Total {
+			break
+		}
+	}
+	if len(cards) > n {
+		cards = cards[:n]
+	}
+
+	submit := func(c card, grade review.Grade) error {
+		body, err := json.Marshal(api.CreateReviewRequest{EntryID: c.ID, Grade: string(grade)})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, base+"/reviews", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusCreated {
+			return responseError(res)
+		}
+		return nil
+	}
+	return cards, submit, nil
+}
+
+func fetchEntries(client *http.Client, req *http.Request) ([]api.Entry, api.Meta, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, api.Meta{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, api.Meta{}, responseError(res)
+	}
+	var entries []api.Entry
+	body := api.Envelope{Data: &entries}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, api.Meta{}, fmt.Errorf("invalid response: %w", err)
+	}
+	if body.Meta == nil {
+		return nil, api.Meta{}, errors.New("invalid response: no pagination")
+	}
+	return entries, *body.Meta, nil
+}
+
+// responseError returns the error of the API response.
+func responseError(res *http.Response) error {
+	var body api.ErrorEnvelope
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || len(body.Error.Message) == 0 {
+		return fmt.Errorf("the server responded %s", res.Status)
+	}
+	return fmt.Errorf("the server responded %s: %s", res.Status, body.Error.Message)
+}
+
+// localQuiz reads up to n due entries of the user from the database of
+// the configuration and submits the answers to it.
+func localQuiz(idOrEmail string, n int) ([]card, submitFunc, error) {
+	if _, _, err := openDB(); err != nil {
+		return nil, nil, err
+	}
+	user, err := findUser(idOrEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := context.Background()
+	var words []types.GermanWord
+	err = review.Due(ctx, user.ID, 0, time.Now()).Order(review.Order).Limit(n).Find(&words).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	cards := make([]card, len(words))
+	for i, w := range words {
+		cards[i] = card{ID: w.ID, Word: w.GermanWord, Definition: w.Definition, Example: w.Example}
+	}
+	submit := func(c card, grade review.Grade) error {
+		_, _, err := review.Submit(ctx, user.ID, c.ID, grade, time.Now())
+		return err
+	}
+	return cards, submit, nil
+}
+
+// session is an interactive review in the terminal.
+type session struct {
+	in  *bufio.Reader
+	out io.Writer
+	// clear clears the screen between the cards.
+	clear  bool
+	submit submitFunc
+}
+
+// Results of a card.
+const (
+	gradeCorrect = "correct"
+	gradeKnown   = "known"
+	gradeMissed  = "missed"
+)
+
+// schedulerGrades are the grades of the results for the scheduler. A
+// word the user had to grade themselves, e.g. for a typo, was hard.
+var schedulerGrades = map[string]review.Grade{
+	gradeCorrect: review.Good,
+	gradeKnown:   review.Hard,
+	gradeMissed:  review.Again,
+}
+
+// review asks for the cards and submits the answers, it stops at the
+// end of the input or if an answer can't be submitted.
+func (s *session) review(cards []card) error {
+	var missed []card
+	known := 0
+	var err error
+	for i, c := range cards {
+		if s.clear {
+			fmt.Fprint(s.out, "\033[H\033[2J")
+		}
+		fmt.Fprintf(s.out, "Card %d of %d\n\n%s\n", i+1, len(cards), c.Definition)
+		if len(c.Example) > 0 {
+			fmt.Fprintf(s.out, "\n  %s\n", mask(c.Example, c.Word))
+		}
+		answer, ok := s.prompt("\nYour answer: ")
+		if !ok {
+			break
+		}
+		result := s.grade(c, answer)
+		if err = s.submit(c, schedulerGrades[result]); err != nil {
+			err = fmt.Errorf("failed to save the answer to %s: %w", c.Word, err)
+			break
+		}
+		switch result {
+		case gradeCorrect, gradeKnown:
+			known++
+		case gradeMissed:
+			missed = append(missed, c)
+		}
+	}
+
+	reviewed := known + len(missed)
+	fmt.Fprintf(s.out, "\nYou knew %d of %d words.\n", known, reviewed)
+	if len(missed) > 0 {
+		fmt.Fprintln(s.out, "\nWords to practice:")
+		for _, c := range missed {
+			fmt.Fprintf(s.out, "  %s\n", c.Word)
+		}
+	}
+	return err
+}
+
+// grade checks the typed answer. Exact answers are correct, otherwise
+// the word is shown and the user grades themselves, e.g. for a typo or
+// a missing article.
+func (s *session) grade(c card, answer string) string {
+	if normalize(answer) == normalize(c.Word) {
+		fmt.Fprintln(s.out, "Correct!")
+		s.prompt("\nPress enter to continue ")
+		return gradeCorrect
+	}
+	fmt.Fprintf(s.out, "The answer is: %s\n", c.Word)
+	for {
+		reply, ok := s.prompt("Did you know it? [y/n] ")
+		if !ok {
+			return gradeMissed
+		}
+		switch strings.ToLower(strings.TrimSpace(reply)) {
+		case "y", "yes":
+			return gradeKnown
+		case "n", "no":
+			return gradeMissed
+		}
+	}
+}
+
+// prompt reads a line, ok is false at the end of the input.
+func (s *session) prompt(text string) (string, bool) {
+	fmt.Fprint(s.out, text)
+	line, err := s.in.ReadString('\n')
+	if err != nil && len(line) == 0 {
+		fmt.Fprintln(s.out)
+		return "", false
+	}
+	return strings.TrimRight(line, "\r\n"), true
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+var articles = map[string]bool{"der": true, "die": true, "das": true, "sich": true}
+
+// mask hides the word in the example, without its article.
+func mask(example, word string) string {
+	fields := strings.Fields(word)
+	if len(fields) > 1 && articles[strings.ToLower(fields[0])] {
+		fields = fields[1:]
+	}
+	for _, f := range fields {
+		// \b only knows ASCII letters, the words have umlauts.
+		re := regexp.MustCompile(`(?i)(^|[^\p{L}])` + regexp.QuoteMeta(f) + `([^\p{L}]|$)`)
+		example = re.ReplaceAllString(example, "${1}"+strings.Repeat("_", len([]rune(f)))+"${2}")
+	}
+	return example
+}
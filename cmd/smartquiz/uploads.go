@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+)
+
+var uploadCommands = map[string]command{
+	"list":  {"list the uploads the AI extraction failed for", runUploadList},
+	"retry": {"run the AI extraction of failed uploads again", runUploadRetry},
+}
+
+func runUploadList(args []string) int {
+	fs := flag.NewFlagSet("uploads list", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "list at most `n` uploads, newest first")
+	fs.Parse(args)
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	var uploads []types.FailedUpload
+	if err := db.Get().Omit("image").Order("id desc").Limit(*limit).Find(&uploads).Error; err != nil {
+		return fail(err)
+	}
+	w := table("ID", "USER", "SOURCE", "SIZE", "ERROR", "CREATED")
+	for _, u := range uploads {
+		row(w, u.ID, u.UserID, u.Source, u.ImageSize, truncate(u.Error, 60), u.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	return 0
+}
+
+func runUploadRetry(args []string) int {
+	fs := flag.NewFlagSet("uploads retry", flag.ExitOnError)
+	all := fs.Bool("all", false, "retry all failed uploads, oldest first")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: smartquiz uploads retry (-all | <upload id>...)\n\n"+
+			"Runs the AI extraction of the uploads again, with the quota of their\n"+
+			"users. The entries are created and the uploads removed on success.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *all == (fs.NArg() > 0) {
+		fs.Usage()
+		return 2
+	}
+	ids := make([]uint, fs.NArg())
+	for i, arg := range fs.Args() {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("invalid upload id %q", arg))
+		}
+		ids[i] = uint(id)
+	}
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	// The IDs first, the pictures are loaded one at a time.
+	query := db.Get().Model(&types.FailedUpload{}).Order("id")
+	if !*all {
+		query = query.Where("id IN ?", ids)
+	}
+	var found []uint
+	if err := query.Pluck("id", &found).Error; err != nil {
+		return fail(err)
+	}
+	if !*all && len(found) != len(ids) {
+		return fail(fmt.Errorf("found %d of %d uploads", len(found), len(ids)))
+	}
+
+	ctx := context.Background()
+	failed := 0
+	for _, id := range found {
+		var upload types.FailedUpload
+		if err := db.Get().First(&upload, id).Error; err != nil {
+			return fail(err)
+		}
+		word, err := ai.RetryFailedUpload(ctx, upload)
+		if err != nil {
+			fmt.Printf("upload %d: %v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Printf("upload %d: created entry %d, %s\n", id, word.ID, word.GermanWord)
+	}
+	if failed > 0 {
+		return fail(fmt.Errorf("%d of %d uploads failed again", failed, len(found)))
+	}
+	return 0
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"smartquiz/app/db"
+	"smartquiz/plugins/auth"
+
+	"golang.org/x/term"
+)
+
+var userCommands = map[string]command{
+	"list":           {"list the users", runUserList},
+	"create":         {"create a verified user", runUserCreate},
+	"verify":         {"mark the email address of a user as verified", userAction("verify", verifyUser)},
+	"disable":        {"disable a user and log them out", userAction("disable", auth.DisableUser)},
+	"enable":         {"enable a disabled user", userAction("enable", auth.EnableUser)},
+	"reset-password": {"set a new password and log the user out", userAction("reset-password", resetPassword)},
+}
+
+func runUserList(args []string) int {
+	fs := flag.NewFlagSet("users list", flag.ExitOnError)
+	query := fs.String("q", "", "only list users whose email address or name contains `text`")
+	limit := fs.Int("limit", 50, "list at most `n` users, newest first")
+	fs.Parse(args)
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	tx := db.Get().Order("id desc").Limit(*limit)
+	if q := strings.TrimSpace(*query); len(q) > 0 {
+		like := "%" + strings.ToLower(q) + "%"
+		tx = tx.Where("lower(email) LIKE ? OR lower(first_name) LIKE ? OR lower(last_name) LIKE ?", like, like, like)
+	}
+	var users []auth.User
+	if err := tx.Find(&users).Error; err != nil {
+		return fail(err)
+	}
+	w := table("ID", "EMAIL", "NAME", "ROLE", "VERIFIED", "DISABLED", "CREATED")
+	for _, u := range users {
+		row(w, u.ID, u.Email, strings.TrimSpace(u.FirstName+" "+u.LastName), u.Role,
+			yesNo(u.EmailVerifiedAt.Valid), yesNo(u.Disabled()), u.CreatedAt.Format("2006-01-02"))
+	}
+	w.Flush()
+	return 0
+}
+
+func runUserCreate(args []string) int {
+	fs := flag.NewFlagSet("users create", flag.ExitOnError)
+	var values auth.SignupFormValues
+	fs.StringVar(&values.Email, "email", "", "email `address` of the user, required")
+	fs.StringVar(&values.FirstName, "first-name", "", "first `name`, required")
+	fs.StringVar(&values.LastName, "last-name", "", "last `name`, required")
+	admin := fs.Bool("admin", false, "give the user the admin role")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: smartquiz users create -email <address> -first-name <name> -last-name <name> [-admin]\n\n"+
+			"The password is prompted for, or read from the first line of stdin.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if _, _, err := openDB(); err != nil {
+		return fail(err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fail(err)
+	}
+	values.Password = password
+	values.PasswordConfirm = password
+	role := auth.RoleUser
+	if *admin {
+		role = auth.RoleAdmin
+	}
+	user, err := auth.CreateUser(context.Background(), values, role)
+	if err != nil {
+		return fail(err)
+	}
+	// Users created by an operator don't need to confirm their email.
+	if err := auth.VerifyEmail(context.Background(), &user); err != nil {
+		return fail(err)
+	}
+	logAction("create", user)
+	fmt.Printf("created user %d %s\n", user.ID, user.Email)
+	return 0
+}
+
+func verifyUser(ctx context.Context, user *auth.User) error {
+	if user.EmailVerifiedAt.Valid {
+		return nil
+	}
+	return auth.VerifyEmail(ctx, user)
+}
+
+func resetPassword(ctx context.Context, user *auth.User) error {
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	return auth.SetPassword(ctx, user, password)
+}
+
+// userAction returns a command that runs action on the user given by
+// ID or email address.
+func userAction(name string, action func(context.Context, *auth.User) error) func([]string) int {
+	return func(args []string) int {
+		fs := flag.NewFlagSet("users "+name, flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: smartquiz users %s <id or email>\n", name)
+		}
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+		if _, _, err := openDB(); err != nil {
+			return fail(err)
+		}
+		user, err := findUser(fs.Arg(0))
+		if err != nil {
+			return fail(err)
+		}
+		if err := action(context.Background(), &user); err != nil {
+			return fail(err)
+		}
+		logAction(name, user)
+		fmt.Printf("%s: done for user %d %s\n", name, user.ID, user.Email)
+		return 0
+	}
+}
+
+// findUser finds a user by ID or email address.
+func findUser(idOrEmail string) (auth.User, error) {
+	var user auth.User
+	tx := db.Get()
+	if id, err := strconv.ParseUint(idOrEmail, 10, 64); err == nil {
+		tx = tx.Where("id = ?", id)
+	} else {
+		tx = tx.Where("email = ?", idOrEmail)
+	}
+	if err := tx.Limit(1).Find(&user).Error; err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, fmt.Errorf("no user %s", idOrEmail)
+	}
+	return user, nil
+}
+
+// readPassword prompts for a password twice on a terminal. Otherwise
+// the first line of stdin is the password, for scripts.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && len(line) == 0 {
+			return "", errors.New("expected the password on stdin")
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	confirm, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(password) != string(confirm) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(password), nil
+}
+
+// logAction writes the actions to the log for auditing, like those of
+// the admin pages.
+func logAction(action string, user auth.User) {
+	slog.Info("admin action", "action", action, "admin", "cli", "user", user.ID)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
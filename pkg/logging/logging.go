@@ -0,0 +1,211 @@
+// Package logging sets up log/slog: text or JSON output, attributes of
+// the current request like its ID, and a handler that redacts secrets
+// and binary data before a record is written.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxValueLength is the length strings are truncated to, so a raw API
+// response can't flood the logs.
+const maxValueLength = 2048
+
+// Options of New.
+type Options struct {
+	Level slog.Leveler
+	// JSON selects JSON output instead of key=value text.
+	JSON bool
+	// Secrets are values replaced wherever they appear in a record,
+	// e.g. the API key inside an error message. Values shorter than 4
+	// characters are ignored, they would mask too much.
+	Secrets []string
+}
+
+// New returns a logger writing to w that redacts every record.
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	var h slog.Handler
+	if opts.JSON {
+		h = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		h = slog.NewTextHandler(w, handlerOpts)
+	}
+	return slog.New(NewHandler(h, opts.Secrets))
+}
+
+// Handler redacts the records before passing them on, and adds the
+// attributes of the context, see With.
+type Handler struct {
+	next    slog.Handler
+	secrets *strings.Replacer
+}
+
+// NewHandler wraps next with redaction of the given secret values.
+func NewHandler(next slog.Handler, secrets []string) *Handler {
+	var pairs []string
+	for _, s := range secrets {
+		if len(s) >= 4 {
+			pairs = append(pairs, s, redacted)
+		}
+	}
+	return &Handler{next: next, secrets: strings.NewReplacer(pairs...)}
+}
+
+const redacted = "[redacted]"
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, h.scrub(r.Message), r.PC)
+	for _, a := range contextAttrs(ctx) {
+		record.AddAttrs(h.redact(a))
+	}
+	// The trace ID finds the trace of a log line, if it was recorded.
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && sc.IsSampled() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	list := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		list[i] = h.redact(a)
+	}
+	return &Handler{next: h.next.WithAttrs(list), secrets: h.secrets}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), secrets: h.secrets}
+}
+
+// redact replaces the values of secret keys, byte slices and secret
+// values inside strings.
+func (h *Handler) redact(a slog.Attr) slog.Attr {
+	if isSecretKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.scrub(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		list := make([]any, len(attrs))
+		for i, attr := range attrs {
+			list[i] = h.redact(attr)
+		}
+		return slog.Group(a.Key, list...)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case []byte:
+			return slog.String(a.Key, fmt.Sprintf("[%d bytes]", len(x)))
+		case error:
+			return slog.String(a.Key, h.scrub(x.Error()))
+		default:
+			// Structs may hold secrets or images in any field, only
+			// their scrubbed text form is logged.
+			return slog.String(a.Key, h.scrub(fmt.Sprintf("%+v", x)))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+var (
+	dataURL = regexp.MustCompile(`data:[\w/.+-]+;base64,[A-Za-z0-9+/=]+`)
+	// tokenParam matches the value of query parameters like token= or
+	// access_token=, e.g. in the links of reset and verify mails.
+	tokenParam = regexp.MustCompile(`(?i)([?&][\w.-]*token=)[^&#\s"']+`)
+	// jwt matches JSON Web Tokens, their header and payload start with
+	// the base64 encoding of '{"'.
+	jwt = regexp.MustCompile(`eyJ[\w-]+\.eyJ[\w-]+\.[\w-]*`)
+)
+
+func (h *Handler) scrub(s string) string {
+	s = h.secrets.Replace(s)
+	s = dataURL.ReplaceAllString(s, "[data url]")
+	s = tokenParam.ReplaceAllString(s, "${1}"+redacted)
+	s = jwt.ReplaceAllString(s, redacted)
+	if len(s) > maxValueLength {
+		s = s[:maxValueLength] + "...[truncated]"
+	}
+	return s
+}
+
+// isSecretKey reports whether the attribute key names a secret, like
+// password, client_secret, api_key or sessionToken.
+func isSecretKey(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "", ".", "").Replace(key))
+	for _, s := range []string{"password", "secret", "apikey", "authorization", "cookie"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(k, "token")
+}
+
+type ctxKey struct{}
+
+// attrs are shared by every context derived from the one they were
+// added to, so a middleware can add attributes an outer one logs.
+type attrs struct {
+	mu   sync.Mutex
+	list []slog.Attr
+}
+
+// With returns a context whose records get the attributes, in addition
+// to the ones of ctx. The args are key-value pairs like in slog.Info.
+func With(ctx context.Context, args ...any) context.Context {
+	a := &attrs{list: append(contextAttrs(ctx), argsToAttrs(args)...)}
+	return context.WithValue(ctx, ctxKey{}, a)
+}
+
+// Add adds attributes to the context created by With. Unlike With the
+// attributes are seen by the callers higher up that share the context,
+// e.g. the request log of Middleware gets the user ID added by the
+// authentication. It does nothing if ctx has no attributes.
+func Add(ctx context.Context, args ...any) {
+	a, ok := ctx.Value(ctxKey{}).(*attrs)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.list = append(a.list, argsToAttrs(args)...)
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	a, ok := ctx.Value(ctxKey{}).(*attrs)
+	if !ok {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]slog.Attr{}, a.list...)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	list := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		list = append(list, a)
+		return true
+	})
+	return list
+}
@@ -0,0 +1,117 @@
+// Package ratelimit implements an in-process token bucket rate limiter
+// with one bucket per key, usable as chi middleware.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Buckets that have been full for this long are forgotten.
+const pruneInterval = 10 * time.Minute
+
+// KeyFunc returns the bucket key of a request, for example the client
+// IP address or the ID of the logged in user.
+type KeyFunc func(r *http.Request) string
+
+// Limiter allows limit events per interval for every key, with bursts
+// of up to limit events.
+type Limiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens per second
+	burst     float64
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Limiter allowing limit events per the given interval.
+func New(limit int, per time.Duration) *Limiter {
+	return NewWithClock(limit, per, time.Now)
+}
+
+// NewWithClock is like New but reads the time from now, which lets
+// tests of callers control the time.
+func NewWithClock(limit int, per time.Duration, now func() time.Time) *Limiter {
+	return &Limiter{
+		rate:      float64(limit) / per.Seconds(),
+		burst:     float64(limit),
+		buckets:   make(map[string]*bucket),
+		lastPrune: now(),
+		now:       now,
+	}
+}
+
+// Allow takes a token from the bucket of key. If the bucket is empty it
+// returns false and how long to wait until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Reset forgets the bucket of key, refilling it.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, key)
+}
+
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware rejects requests with 429 Too Many Requests once the bucket
+// of their key is empty. onLimit can be used to render a custom response,
+// a plain text response is written when it is nil.
+func (l *Limiter) Middleware(key KeyFunc, onLimit func(w http.ResponseWriter, r *http.Request)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(key(r))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				if onLimit != nil {
+					onLimit(w, r)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Now()
+	l := New(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("expected the 4th request to be limited")
+	}
+	if wait != 20*time.Second {
+		t.Fatalf("expected to wait 20s, got %s", wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("expected other keys to have their own bucket")
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("expected a token to be refilled after 20s")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("expected only one token to be refilled")
+	}
+
+	l.Reset("a")
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("expected reset to refill the bucket")
+	}
+}
+
+func TestLimiterMiddleware(t *testing.T) {
+	l := New(1, time.Hour)
+	h := l.Middleware(func(r *http.Request) string { return r.RemoteAddr }, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "3600" {
+		t.Fatalf("expected Retry-After 3600, got %q", rec.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anthdm/superkit/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event wraps the payload of an event emitted with Emit. The superkit
+// event bus calls the handlers with a background context, the carrier
+// brings the trace context of the emitter along.
+type Event struct {
+	Carrier propagation.MapCarrier
+	Payload any
+}
+
+var (
+	subscribersMu sync.Mutex
+	// subscribers counts the handlers subscribed with Subscribe by topic.
+	subscribers = map[string]int{}
+	// pending counts the calls of these handlers that haven't returned
+	// yet, from the moment the event is emitted.
+	pending sync.WaitGroup
+)
+
+// Emit emits the payload on the superkit event bus as an Event carrying
+// the trace context of ctx. The calls of the handlers subscribed with
+// Subscribe are counted before Emit returns, see Wait.
+func Emit(ctx context.Context, topic string, payload any) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	subscribersMu.Lock()
+	pending.Add(subscribers[topic])
+	subscribersMu.Unlock()
+	event.Emit(topic, Event{Carrier: carrier, Payload: payload})
+}
+
+// Subscribe subscribes h, wrapped in Handler, to the topic. Wait waits
+// for its calls. Handlers must be subscribed before events of the topic
+// are emitted and can't be unsubscribed.
+func Subscribe(topic string, h event.HandlerFunc) {
+	subscribersMu.Lock()
+	subscribers[topic]++
+	subscribersMu.Unlock()
+	h = Handler(topic, h)
+	event.Subscribe(topic, func(ctx context.Context, v any) {
+		if _, ok := v.(Event); ok {
+			defer pending.Done()
+		}
+		h(ctx, v)
+	})
+}
+
+// Wait waits until the handlers subscribed with Subscribe returned for
+// every event emitted with Emit, or the context is done. No events must
+// be emitted while waiting.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler unwraps events emitted with Emit before passing them to h.
+// The handler runs in a span of the trace of the emitter. Payloads
+// emitted with event.Emit are passed on as they are.
+func Handler(topic string, h event.HandlerFunc) event.HandlerFunc {
+	return func(ctx context.Context, v any) {
+		e, ok := v.(Event)
+		if !ok {
+			h(ctx, v)
+			return
+		}
+		ctx = otel.GetTextMapPropagator().Extract(ctx, e.Carrier)
+		ctx, span := Start(ctx, "event "+topic, trace.WithSpanKind(trace.SpanKindConsumer))
+		defer span.End()
+		h(ctx, e.Payload)
+	}
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// GormPlugin creates a client span for every query of a gorm instance,
+// register it with instance.Use(tracing.GormPlugin{}). Queries must be
+// run WithContext to be part of the trace of a request. The SQL is
+// recorded with its placeholders, the parameters may be personal data.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startQuery("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endQuery),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startQuery("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endQuery),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startQuery("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endQuery),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuery("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endQuery),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startQuery("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endQuery),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuery("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endQuery),
+	)
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// Queries outside of a trace, e.g. of the jobs, would each start
+		// a trace of their own.
+		if db.Statement == nil || db.Statement.Context == nil ||
+			!trace.SpanFromContext(db.Statement.Context).SpanContext().IsValid() {
+			return
+		}
+		name := "db." + operation
+		if len(db.Statement.Table) > 0 {
+			name += " " + db.Statement.Table
+		}
+		_, span := Start(db.Statement.Context, name, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			))
+		db.InstanceSet(spanKeyName, span)
+	}
+}
+
+// spanKeyName stores the span of the statement between the callbacks.
+const spanKeyName = "tracing:span"
+
+func endQuery(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKeyName)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthdm/superkit/event"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record installs a provider that keeps the ended spans in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/entries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/entries/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /entries/{id}" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the trace of the caller, got %s", span.SpanContext().TraceID())
+	}
+	if route := attr(span, "http.route").AsString(); route != "/entries/{id}" {
+		t.Errorf("unexpected route %q", route)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected an error status, got %v", span.Status().Code)
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := record(t)
+	instance, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := instance.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	type Entry struct {
+		ID   uint
+		Word string
+	}
+	if err := instance.AutoMigrate(&Entry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a parent span queries aren't traced.
+	instance.Create(&Entry{Word: "Haus"})
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("expected no spans outside of a trace, got %d", n)
+	}
+
+	ctx, parent := Start(context.Background(), "test")
+	var entries []Entry
+	if err := instance.WithContext(ctx).Where("word = ?", "Haus").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "db.query entries" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the query span to be a child of the parent span")
+	}
+	if query := attr(span, "db.query.text").AsString(); query != "SELECT * FROM `entries` WHERE word = ?" {
+		t.Errorf("unexpected query %q", query)
+	}
+	if rows := attr(span, "db.rows_affected").AsInt64(); rows != 1 {
+		t.Errorf("expected 1 row, got %d", rows)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	recorder := record(t)
+	done := make(chan any, 1)
+	sub := event.Subscribe("test.event", Handler("test.event", func(ctx context.Context, v any) {
+		_, span := Start(ctx, "handle")
+		span.End()
+		done <- v
+	}))
+	defer event.Unsubscribe(sub)
+
+	ctx, parent := Start(context.Background(), "request")
+	Emit(ctx, "test.event", "payload")
+	parent.End()
+	if v := <-done; v != "payload" {
+		t.Errorf("expected the payload, got %v", v)
+	}
+
+	// The event span ends after the handler returned.
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("expected span %q in the trace of the emitter", span.Name())
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	release := make(chan struct{})
+	Subscribe("test.wait", func(ctx context.Context, v any) {
+		<-release
+	})
+	Emit(context.Background(), "test.wait", nil)
+
+	// The handler is counted before it started to run.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected to wait for the handler, got %v", err)
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Wait(ctx); err != nil {
+		t.Fatalf("expected the handler to return, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"github.com/go-chi/chi/v5"
+)
+
+// PromptRow is an embedded prompt and the version that is in use.
+type PromptRow struct {
+	Builtin ai.Prompt
+	Active  ai.Prompt
+}
+
+type PromptFormValues struct {
+	Template string `form:"template"`
+}
+
+// HandlePromptList lists the latest embedded prompts and their overrides.
+func HandlePromptList(kit *kit.Kit) error {
+	var rows []PromptRow
+	for _, builtin := range ai.Prompts().List() {
+		active, err := ai.GetPrompt(kit.Request.Context(), builtin.Name, builtin.Language)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, PromptRow{Builtin: builtin, Active: active})
+	}
+	return kit.Render(PromptList(rows))
+}
+
+func HandlePromptShow(kit *kit.Kit) error {
+	row, err := findPrompt(kit)
+	if err != nil {
+		return err
+	}
+	return kit.Render(PromptShow(row, PromptFormValues{Template: row.Active.Text}, v.Errors{}))
+}
+
+// HandlePromptUpdate stores a new override of the prompt. It's used for
+// the next request, the version of the override is stored with the
+// entries it generates.
+func HandlePromptUpdate(kit *kit.Kit) error {
+	row, err := findPrompt(kit)
+	if err != nil {
+		return err
+	}
+	values := PromptFormValues{Template: kit.FormValue("template")}
+	if err := ai.ValidatePrompt(row.Builtin.Name, row.Builtin.Language, values.Template); err != nil {
+		errors := v.Errors{}
+		errors.Add("template", err.Error())
+		return kit.Render(PromptShow(row, values, errors))
+	}
+
+	override := types.PromptOverride{
+		Name:      row.Builtin.Name,
+		Language:  row.Builtin.Language,
+		Template:  values.Template,
+		CreatedBy: kit.Auth().(auth.Auth).UserID,
+	}
+	if err := db.Get().Create(&override).Error; err != nil {
+		return err
+	}
+	logPromptAction(kit, "overrode prompt", row.Builtin)
+	return redirectToPrompt(kit, row.Builtin)
+}
+
+// HandlePromptReset deletes the overrides, the embedded prompt is used
+// again.
+func HandlePromptReset(kit *kit.Kit) error {
+	row, err := findPrompt(kit)
+	if err != nil {
+		return err
+	}
+	err = db.Get().Delete(&types.PromptOverride{}, "name = ? AND language = ?", row.Builtin.Name, row.Builtin.Language).Error
+	if err != nil {
+		return err
+	}
+	logPromptAction(kit, "reset prompt", row.Builtin)
+	return redirectToPrompt(kit, row.Builtin)
+}
+
+func findPrompt(kit *kit.Kit) (PromptRow, error) {
+	name, language := chi.URLParam(kit.Request, "name"), chi.URLParam(kit.Request, "language")
+	for _, builtin := range ai.Prompts().List() {
+		if builtin.Name == name && builtin.Language == language {
+			active, err := ai.GetPrompt(kit.Request.Context(), name, language)
+			return PromptRow{Builtin: builtin, Active: active}, err
+		}
+	}
+	return PromptRow{}, fmt.Errorf("no prompt %s in %s", name, language)
+}
+
+func redirectToPrompt(kit *kit.Kit, p ai.Prompt) error {
+	return kit.Redirect(http.StatusSeeOther, promptURL(p))
+}
+
+func promptURL(p ai.Prompt) string {
+	return fmt.Sprintf("/admin/prompts/%s/%s", p.Name, p.Language)
+}
+
+func logPromptAction(kit *kit.Kit, action string, p ai.Prompt) {
+	slog.InfoContext(kit.Request.Context(), "admin action", "action", action, "admin", kit.Auth().(auth.Auth).UserID, "prompt", p.Name, "language", p.Language)
+}
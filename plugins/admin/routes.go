@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"expvar"
+	"net/http"
+
+	"smartquiz/app/views/errors"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+)
+
+// InitializeRoutes mounts the admin console under /admin. Only users
+// with the admin role can use it, everyone else gets a 404.
+func InitializeRoutes(router chi.Router) {
+	authConfig := kit.AuthenticationConfig{
+		AuthFunc:    auth.AuthenticateUser,
+		RedirectURL: "/login",
+	}
+
+	router.Route("/admin", func(admin chi.Router) {
+		admin.Use(kit.WithAuthentication(authConfig, true))
+		admin.Use(requireAdmin)
+
+		admin.Get("/", http.RedirectHandler("/admin/users", http.StatusSeeOther).ServeHTTP)
+		admin.Get("/users", kit.Handler(HandleUserList))
+		admin.Get("/users/{id}", kit.Handler(HandleUserShow))
+		admin.Post("/users/{id}/verify", kit.Handler(HandleUserVerify))
+		admin.Post("/users/{id}/disable", kit.Handler(HandleUserDisable))
+		admin.Post("/users/{id}/enable", kit.Handler(HandleUserEnable))
+		admin.Post("/users/{id}/impersonate", kit.Handler(HandleUserImpersonate))
+		admin.Get("/usage", kit.Handler(HandleUsageIndex))
+		admin.Get("/uploads", kit.Handler(HandleUploadList))
+		admin.Get("/prompts", kit.Handler(HandlePromptList))
+		admin.Get("/prompts/{name}/{language}", kit.Handler(HandlePromptShow))
+		admin.Post("/prompts/{name}/{language}", kit.Handler(HandlePromptUpdate))
+		admin.Post("/prompts/{name}/{language}/reset", kit.Handler(HandlePromptReset))
+		// Runtime counters like the AI cache hits and misses.
+		admin.Handle("/debug/vars", expvar.Handler())
+	})
+}
+
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, ok := r.Context().Value(kit.AuthKey{}).(auth.Auth)
+		if !ok || !auth.IsAdmin() {
+			w.WriteHeader(http.StatusNotFound)
+			errors.Error404().Render(r.Context(), w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
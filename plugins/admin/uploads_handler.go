@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+const uploadsPerPage = 50
+
+// FailedUpload is a failed extraction with the email of the user.
+type FailedUpload struct {
+	ID          uint
+	UserID      uint
+	Email       string
+	Source      string
+	Language    string
+	ImageSize   int
+	ImageSHA256 string
+	Error       string
+	CreatedAt   time.Time
+}
+
+type UploadListData struct {
+	Uploads []FailedUpload
+	Page    int
+	HasNext bool
+}
+
+// HandleUploadList lists the uploads the AI extraction failed for,
+// newest first.
+func HandleUploadList(kit *kit.Kit) error {
+	page, err := strconv.Atoi(kit.Request.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	var uploads []FailedUpload
+	// Fetch one more to know if there is a next page.
+	err = db.Get().Model(&types.FailedUpload{}).
+		// Not the pictures, they are up to 10 MB each.
+		Select("failed_uploads.id, failed_uploads.user_id, users.email, failed_uploads.source, failed_uploads.language, " +
+			"failed_uploads.image_size, failed_uploads.image_sha256, failed_uploads.error, failed_uploads.created_at").
+		Joins("LEFT JOIN users ON users.id = failed_uploads.user_id").
+		Order("failed_uploads.id desc").
+		Limit(uploadsPerPage + 1).
+		Offset((page - 1) * uploadsPerPage).
+		Scan(&uploads).Error
+	if err != nil {
+		return err
+	}
+	data := UploadListData{Page: page, HasNext: len(uploads) > uploadsPerPage}
+	if data.HasNext {
+		uploads = uploads[:uploadsPerPage]
+	}
+	data.Uploads = uploads
+	return kit.Render(UploadList(data))
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"time"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/app/types"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+// UserUsage is the AI usage of a single user in the current month.
+type UserUsage struct {
+	UserID   uint
+	Email    string
+	Requests int64
+	Tokens   int64
+	Cost     float64
+}
+
+type UsagePageData struct {
+	Since time.Time
+	Users []UserUsage
+	Total ai.UsageSummary
+}
+
+// HandleUsageIndex lists the AI usage and cost of the current month per
+// user, most expensive first.
+func HandleUsageIndex(kit *kit.Kit) error {
+	data := UsagePageData{Since: ai.StartOfMonth(time.Now())}
+	err := db.Get().Model(&types.AIUsage{}).
+		Select("ai_usages.user_id, users.email, count(*) AS requests, sum(ai_usages.total_tokens) AS tokens, sum(ai_usages.cost) AS cost").
+		Joins("LEFT JOIN users ON users.id = ai_usages.user_id").
+		Where("ai_usages.created_at >= ?", data.Since).
+		Group("ai_usages.user_id, users.email").
+		Order("cost desc").
+		Scan(&data.Users).Error
+	if err != nil {
+		return err
+	}
+	for _, u := range data.Users {
+		data.Total.Requests += u.Requests
+		data.Total.Tokens += u.Tokens
+		data.Total.Cost += u.Cost
+	}
+	return kit.Render(UsageIndex(data))
+}
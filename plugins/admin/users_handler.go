@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/ai"
+	"smartquiz/app/db"
+	"smartquiz/plugins/auth"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+)
+
+const usersPerPage = 50
+
+type UserListData struct {
+	Users   []auth.User
+	Query   string
+	Page    int
+	HasNext bool
+}
+
+type UserPageData struct {
+	User           auth.User
+	ActiveSessions int64
+	APITokens      int64
+	LoginAttempts  []auth.LoginAttempt
+	AIUsage        ai.UsageSummary
+	CurrentUserID  uint
+	// Error is shown above the actions, e.g. when one was refused.
+	Error string
+}
+
+// HandleUserList lists the users, optionally filtered by a search for
+// their email address or name.
+func HandleUserList(kit *kit.Kit) error {
+	query := strings.TrimSpace(kit.Request.URL.Query().Get("q"))
+	page, err := strconv.Atoi(kit.Request.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	tx := db.Get().Order("id desc")
+	if len(query) > 0 {
+		like := "%" + strings.ToLower(query) + "%"
+		tx = tx.Where("lower(email) LIKE ? OR lower(concat(first_name, ' ', last_name)) LIKE ?", like, like)
+	}
+	var users []auth.User
+	// Fetch one more to know if there is a next page.
+	err = tx.Limit(usersPerPage + 1).Offset((page - 1) * usersPerPage).Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	data := UserListData{
+		Query:   query,
+		Page:    page,
+		HasNext: len(users) > usersPerPage,
+	}
+	if data.HasNext {
+		users = users[:usersPerPage]
+	}
+	data.Users = users
+	return kit.Render(UserList(data))
+}
+
+func HandleUserShow(kit *kit.Kit) error {
+	user, err := findUser(kit)
+	if err != nil {
+		return err
+	}
+	data, err := userPageData(kit, user)
+	if err != nil {
+		return err
+	}
+	return kit.Render(UserShow(data))
+}
+
+func userPageData(kit *kit.Kit, user auth.User) (UserPageData, error) {
+	data := UserPageData{
+		User:          user,
+		CurrentUserID: kit.Auth().(auth.Auth).UserID,
+	}
+	err := db.Get().Model(&auth.Session{}).
+		Where("user_id = ? AND expires_at > ?", user.ID, time.Now()).
+		Count(&data.ActiveSessions).Error
+	if err != nil {
+		return data, err
+	}
+	err = db.Get().Model(&auth.APIToken{}).Where("user_id = ?", user.ID).Count(&data.APITokens).Error
+	if err != nil {
+		return data, err
+	}
+	err = db.Get().
+		Where("lower(email) = ?", strings.ToLower(user.Email)).
+		Order("created_at desc").
+		Limit(20).
+		Find(&data.LoginAttempts).Error
+	if err != nil {
+		return data, err
+	}
+	data.AIUsage, err = ai.UsageSince(kit.Request.Context(), user.ID, ai.StartOfMonth(time.Now()))
+	return data, err
+}
+
+// HandleUserVerify marks the email address of the user as verified, for
+// users that never received their verification email.
+func HandleUserVerify(kit *kit.Kit) error {
+	user, err := findUser(kit)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerifiedAt.Valid {
+		if err := auth.VerifyEmail(kit.Request.Context(), &user); err != nil {
+			return err
+		}
+		logAction(kit, "verified email", user)
+	}
+	return redirectToUser(kit, user)
+}
+
+// HandleUserDisable disables the account and logs the user out on all
+// devices.
+func HandleUserDisable(kit *kit.Kit) error {
+	user, err := findUser(kit)
+	if err != nil {
+		return err
+	}
+	if user.ID == kit.Auth().(auth.Auth).UserID {
+		data, err := userPageData(kit, user)
+		if err != nil {
+			return err
+		}
+		data.Error = "You can't disable your own account."
+		return kit.Render(UserShow(data))
+	}
+	if err := auth.DisableUser(kit.Request.Context(), &user); err != nil {
+		return err
+	}
+	logAction(kit, "disabled user", user)
+	return redirectToUser(kit, user)
+}
+
+func HandleUserEnable(kit *kit.Kit) error {
+	user, err := findUser(kit)
+	if err != nil {
+		return err
+	}
+	if err := auth.EnableUser(kit.Request.Context(), &user); err != nil {
+		return err
+	}
+	logAction(kit, "enabled user", user)
+	return redirectToUser(kit, user)
+}
+
+// HandleUserImpersonate logs the admin in as the user, see
+// auth.Impersonate.
+func HandleUserImpersonate(kit *kit.Kit) error {
+	user, err := findUser(kit)
+	if err != nil {
+		return err
+	}
+	if err := auth.Impersonate(kit, user); err != nil {
+		return err
+	}
+	return kit.Redirect(http.StatusSeeOther, "/")
+}
+
+func findUser(kit *kit.Kit) (auth.User, error) {
+	var user auth.User
+	id, err := strconv.Atoi(chi.URLParam(kit.Request, "id"))
+	if err != nil {
+		return user, err
+	}
+	err = db.Get().First(&user, id).Error
+	return user, err
+}
+
+func redirectToUser(kit *kit.Kit, user auth.User) error {
+	return kit.Redirect(http.StatusSeeOther, fmt.Sprintf("/admin/users/%d", user.ID))
+}
+
+// logAction writes admin actions to the log for auditing.
+func logAction(kit *kit.Kit, action string, user auth.User) {
+	slog.InfoContext(kit.Request.Context(), "admin action", "action", action, "admin", kit.Auth().(auth.Auth).UserID, "user", user.ID)
+}
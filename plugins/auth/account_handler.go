@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// AccountData lets the app include the data it stores for a user in the
+// personal data export, and delete it when the account is purged.
+type AccountData struct {
+	// Export writes the data of the user into the export archive.
+	Export func(userID uint, archive *zip.Writer) error
+	// Purge deletes the data of the user. It runs in the transaction
+	// that deletes the user.
+	Purge func(tx *gorm.DB, userID uint) error
+}
+
+var accountData []AccountData
+
+// RegisterAccountData registers data the app stores for its users.
+func RegisterAccountData(data AccountData) {
+	accountData = append(accountData, data)
+}
+
+type AccountDeleteFormValues struct {
+	Password string `form:"password"`
+}
+
+// HandleAccountExport downloads all data of the user as a zip archive of
+// JSON files.
+func HandleAccountExport(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("smartquiz-export-%s.zip", time.Now().Format("2006-01-02"))
+	kit.Response.Header().Set("Content-Type", "application/zip")
+	kit.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	archive := zip.NewWriter(kit.Response)
+	if err := exportAccount(kit.Request.Context(), user, archive); err != nil {
+		// The headers are already sent, all we can do is to not
+		// finish the archive so it's recognizably broken.
+		slog.ErrorContext(kit.Request.Context(), "failed to export account", "user", user.ID, "err", err)
+		return nil
+	}
+	return archive.Close()
+}
+
+func exportAccount(ctx context.Context, user User, archive *zip.Writer) error {
+	profile := map[string]any{
+		"id":                 user.ID,
+		"email":              user.Email,
+		"first_name":         user.FirstName,
+		"last_name":          user.LastName,
+		"email_verified_at":  nullTime(user.EmailVerifiedAt),
+		"two_factor_enabled": user.TwoFactorEnabled(),
+		"single_sign_on":     len(user.OIDCSubject) > 0,
+		"created_at":         user.CreatedAt,
+		"updated_at":         user.UpdatedAt,
+	}
+	if err := WriteJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	var sessions []Session
+	if err := db.Get().WithContext(ctx).Where("user_id = ?", user.ID).Order("created_at").Find(&sessions).Error; err != nil {
+		return err
+	}
+	sessionExport := make([]map[string]any, len(sessions))
+	for i, s := range sessions {
+		sessionExport[i] = map[string]any{
+			"ip_address": s.IPAddress,
+			"user_agent": s.UserAgent,
+			"created_at": s.CreatedAt,
+			"expires_at": s.ExpiresAt,
+		}
+	}
+	if err := WriteJSON(archive, "sessions.json", sessionExport); err != nil {
+		return err
+	}
+
+	var tokens []APIToken
+	if err := db.Get().WithContext(ctx).Where("user_id = ?", user.ID).Order("created_at").Find(&tokens).Error; err != nil {
+		return err
+	}
+	tokenExport := make([]map[string]any, len(tokens))
+	for i, t := range tokens {
+		tokenExport[i] = map[string]any{
+			"name":         t.Name,
+			"scopes":       t.ScopeList(),
+			"created_at":   t.CreatedAt,
+			"last_used_at": nullTime(t.LastUsedAt),
+			"expires_at":   nullTime(t.ExpiresAt),
+		}
+	}
+	if err := WriteJSON(archive, "api_tokens.json", tokenExport); err != nil {
+		return err
+	}
+
+	var attempts []LoginAttempt
+	if err := db.Get().WithContext(ctx).Where("lower(email) = ?", normalizeEmail(user.Email)).Order("created_at").Find(&attempts).Error; err != nil {
+		return err
+	}
+	if err := WriteJSON(archive, "failed_logins.json", attempts); err != nil {
+		return err
+	}
+
+	for _, data := range accountData {
+		if data.Export == nil {
+			continue
+		}
+		if err := data.Export(user.ID, archive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON adds a file with the indented JSON encoding of v to the
+// export archive.
+func WriteJSON(archive *zip.Writer, name string, v any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func nullTime(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time
+}
+
+// HandleAccountDelete schedules the account for deletion after the
+// password was confirmed and logs the user out everywhere. The account
+// is purged after the grace period, logging in again cancels the
+// deletion.
+func HandleAccountDelete(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var values AccountDeleteFormValues
+	errors, ok := v.Request(kit.Request, &values, passwordConfirmSchema)
+	if !ok {
+		return kit.Render(AccountDeleteForm(errors))
+	}
+
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
+		return err
+	}
+	if isDemoAccount(user.Email) {
+		errors.Add("password", demoAccountMessage)
+		return kit.Render(AccountDeleteForm(errors))
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(values.Password))
+	if err != nil {
+		errors.Add("password", "invalid password")
+		return kit.Render(AccountDeleteForm(errors))
+	}
+
+	purgeAt, err := requestAccountDeletion(kit, user)
+	if err != nil {
+		return err
+	}
+	return kit.Render(AccountDeleted(user.Email, purgeAt))
+}
+
+// HandleAccountDeleteOIDC lets users created by single sign-on, who
+// don't know their password, confirm the deletion by logging in at the
+// identity provider again.
+func HandleAccountDeleteOIDC(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
+		return err
+	}
+	if isDemoAccount(user.Email) {
+		return kit.Render(AccountDeleteError(demoAccountMessage))
+	}
+	if len(user.OIDCSubject) == 0 || !oidcConfigFromConf().Enabled() {
+		return kit.Render(AccountDeleteError("Your account is not linked to single sign-on, confirm the deletion with your password."))
+	}
+	return startOIDC(kit, oidcDeleteAccount, user.ID, reauthenticate...)
+}
+
+// confirmOIDCAccountDelete deletes the account after the round trip to
+// the identity provider started by HandleAccountDeleteOIDC. The provider
+// must have authenticated the user just now, a login at the provider
+// that is still open doesn't confirm anything.
+func confirmOIDCAccountDelete(kit *kit.Kit, userID uint, identity oidcIdentity) error {
+	auth, _ := kit.Auth().(Auth)
+	if !auth.Check() || auth.UserID != userID {
+		return kit.Render(AccountDeleteError("Your session expired, please log in and try again."))
+	}
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, userID).Error; err != nil {
+		return err
+	}
+	if isDemoAccount(user.Email) {
+		return kit.Render(AccountDeleteError(demoAccountMessage))
+	}
+	if identity.Subject != user.OIDCSubject {
+		return kit.Render(AccountDeleteError("You logged in with another account at the identity provider."))
+	}
+	if identity.AuthTime.IsZero() || time.Since(identity.AuthTime) > oidcLoginTimeout {
+		return kit.Render(AccountDeleteError("The identity provider didn't confirm your login, please try again."))
+	}
+	purgeAt, err := requestAccountDeletion(kit, user)
+	if err != nil {
+		return err
+	}
+	return kit.Render(AccountDeletedPage(user.Email, purgeAt))
+}
+
+// requestAccountDeletion schedules the account for deletion, logs the
+// user out everywhere and returns when the account will be purged.
+func requestAccountDeletion(kit *kit.Kit, user User) (time.Time, error) {
+	now := time.Now()
+	err := db.Get().WithContext(kit.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", user.ID).
+			Update("deletion_requested_at", sql.NullTime{Time: now, Valid: true}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&Session{}, "user_id = ?", user.ID).Error
+	})
+	if err != nil {
+		return now, err
+	}
+
+	sess := kit.GetSession(userSessionName)
+	sess.Values = map[any]any{}
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return now, err
+	}
+	return now.Add(accountDeletionGracePeriod()), nil
+}
+
+// cancelAccountDeletion keeps the account of a user that logged in again
+// during the grace period.
+func cancelAccountDeletion(ctx context.Context, user User) error {
+	if !user.DeletionRequestedAt.Valid {
+		return nil
+	}
+	slog.Info("account deletion cancelled", "user", user.ID)
+	return db.Get().WithContext(ctx).Model(&User{}).Where("id = ?", user.ID).
+		Update("deletion_requested_at", sql.NullTime{}).Error
+}
+
+func accountDeletionGracePeriod() time.Duration {
+	return time.Duration(deletionGraceDays()) * 24 * time.Hour
+}
+
+func deletionGraceDays() int {
+	return conf.Get().Auth.AccountDeletionGraceInDays
+}
+
+// PurgeDeletedAccounts deletes accounts whose grace period is over every
+// interval until the context is cancelled.
+func PurgeDeletedAccounts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var users []User
+		deadline := time.Now().Add(-accountDeletionGracePeriod())
+		err := db.Get().Find(&users, "deletion_requested_at <= ?", deadline).Error
+		if err != nil {
+			slog.Error("failed to find deleted accounts", "err", err)
+		}
+		for _, user := range users {
+			if err := PurgeAccount(user); err != nil {
+				slog.Error("failed to purge account", "user", user.ID, "err", err)
+				continue
+			}
+			slog.Info("purged account", "user", user.ID)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeAccount permanently deletes the user with all of its data.
+func PurgeAccount(user User) error {
+	return db.Get().Transaction(func(tx *gorm.DB) error {
+		return PurgeAccountTx(tx, user)
+	})
+}
+
+// PurgeAccountTx is PurgeAccount in the transaction tx.
+func PurgeAccountTx(tx *gorm.DB, user User) error {
+	for _, data := range accountData {
+		if data.Purge == nil {
+			continue
+		}
+		if err := data.Purge(tx, user.ID); err != nil {
+			return err
+		}
+	}
+	for _, model := range []any{&Session{}, &APIToken{}, &RecoveryCode{}} {
+		if err := tx.Unscoped().Delete(model, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+	}
+	err := tx.Delete(&LoginAttempt{}, "lower(email) = ?", normalizeEmail(user.Email)).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&User{}, user.ID).Error
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+func TestPurgeAccount(t *testing.T) {
+	database := dbtest.New(t)
+	requested := sql.NullTime{Time: time.Now().Add(-accountDeletionGracePeriod() - time.Hour), Valid: true}
+	users := []User{
+		{Email: "deleted@example.com", PasswordHash: "x", DeletionRequestedAt: requested},
+		{Email: "kept@example.com", PasswordHash: "x"},
+	}
+	for i := range users {
+		if err := database.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		session := Session{UserID: users[i].ID, Token: users[i].Email, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := database.Create(&session).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.Create(&LoginAttempt{Email: "deleted@example.com", Reason: "password"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PurgeAccount(users[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	database.Unscoped().Model(&User{}).Where("id = ?", users[0].ID).Count(&count)
+	if count != 0 {
+		t.Error("expected the user to be deleted")
+	}
+	database.Unscoped().Model(&Session{}).Where("user_id = ?", users[0].ID).Count(&count)
+	if count != 0 {
+		t.Error("expected the sessions of the user to be deleted")
+	}
+	database.Model(&LoginAttempt{}).Where("email = ?", "deleted@example.com").Count(&count)
+	if count != 0 {
+		t.Error("expected the failed logins of the user to be deleted")
+	}
+	database.Model(&Session{}).Where("user_id = ?", users[1].ID).Count(&count)
+	if count != 1 {
+		t.Error("expected the sessions of other users to be kept")
+	}
+}
+
+func TestAPITokenDuringDeletion(t *testing.T) {
+	database := dbtest.New(t)
+	user := User{Email: "leaving@example.com", PasswordHash: "x"}
+	database.Create(&user)
+	database.Create(&APIToken{UserID: user.ID, TokenHash: hashAPIToken("sq_token"), Scopes: ScopeWrite})
+
+	authenticate := func() bool {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/entries", nil)
+		r.Header.Set("Authorization", "Bearer sq_token")
+		auth, err := AuthenticateAPIToken(&kit.Kit{Response: httptest.NewRecorder(), Request: r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return auth.Check()
+	}
+	if !authenticate() {
+		t.Fatal("expected the token to work")
+	}
+	database.Model(&user).Update("deletion_requested_at", sql.NullTime{Time: time.Now(), Valid: true})
+	if authenticate() {
+		t.Error("expected the token to be rejected during the deletion grace period")
+	}
+}
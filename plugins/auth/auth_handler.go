@@ -1,10 +1,11 @@
 package auth
 
 import (
+	"smartquiz/app/conf"
 	"smartquiz/app/db"
+	"smartquiz/pkg/logging"
 	"database/sql"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -20,6 +21,8 @@ const (
 	userSessionName = "user-session"
 )
 
+const accountDisabledMessage = "your account has been disabled"
+
 var authSchema = v.Schema{
 	"email":    v.Rules(v.Email),
 	"password": v.Rules(v.Required),
@@ -27,7 +30,7 @@ var authSchema = v.Schema{
 
 func HandleLoginIndex(kit *kit.Kit) error {
 	if kit.Auth().Check() {
-		redirectURL := kit.Getenv("SUPERKIT_AUTH_REDIRECT_AFTER_LOGIN", "/profile")
+		redirectURL := conf.Get().Auth.RedirectAfterLogin
 		return kit.Redirect(http.StatusSeeOther, redirectURL)
 	}
 	return kit.Render(LoginIndex(LoginIndexPageData{}))
@@ -40,8 +43,14 @@ func HandleLoginCreate(kit *kit.Kit) error {
 		return kit.Render(LoginForm(values, errors))
 	}
 
+	guard := getLoginGuard()
+	if wait := guard.check(kit.Request, values.Email); wait > 0 {
+		errors.Add("credentials", tooManyAttemptsMessage(wait))
+		return kit.Render(LoginForm(values, errors))
+	}
+
 	var user User
-	err := db.Get().Find(&user, "email = ?", values.Email).Error
+	err := db.Get().WithContext(kit.Request.Context()).Find(&user, "email = ?", values.Email).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			errors.Add("credentials", "invalid credentials")
@@ -51,38 +60,62 @@ func HandleLoginCreate(kit *kit.Kit) error {
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(values.Password))
 	if err != nil {
+		guard.fail(kit.Request, values.Email, "invalid credentials")
 		errors.Add("credentials", "invalid credentials")
 		return kit.Render(LoginForm(values, errors))
 	}
 
-	skipVerify := kit.Getenv("SUPERKIT_AUTH_SKIP_VERIFY", "false")
-	if skipVerify != "true" {
+	if user.Disabled() {
+		errors.Add("credentials", accountDisabledMessage)
+		return kit.Render(LoginForm(values, errors))
+	}
+
+	if !conf.Get().Auth.SkipVerify {
 		if !user.EmailVerifiedAt.Valid {
+			if err := bindVerificationSession(kit, user); err != nil {
+				return err
+			}
 			errors.Add("verified", "please verify your email")
 			return kit.Render(LoginForm(values, errors))
 		}
 	}
 
-	sessionExpiryStr := kit.Getenv("SUPERKIT_AUTH_SESSION_EXPIRY_IN_HOURS", "48")
-	sessionExpiry, err := strconv.Atoi(sessionExpiryStr)
-	if err != nil {
-		sessionExpiry = 48
+	if user.TwoFactorEnabled() {
+		if err = startTwoFactorLogin(kit, user); err != nil {
+			return err
+		}
+		return kit.Render(TwoFactorLoginForm(v.Errors{}))
+	}
+
+	guard.succeed(user.Email)
+	if err = createSession(kit, user); err != nil {
+		return err
+	}
+	redirectURL := conf.Get().Auth.RedirectAfterLogin
+
+	return kit.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// createSession creates a new Session for the user and stores its token
+// in the session cookie. Logging in cancels a pending account deletion.
+func createSession(kit *kit.Kit, user User) error {
+	if err := cancelAccountDeletion(kit.Request.Context(), user); err != nil {
+		return err
 	}
 	session := Session{
 		UserID:    user.ID,
 		Token:     uuid.New().String(),
-		ExpiresAt: time.Now().Add(time.Hour * time.Duration(sessionExpiry)),
+		IPAddress: clientIP(kit.Request),
+		UserAgent: kit.Request.UserAgent(),
+		ExpiresAt: time.Now().Add(time.Hour * time.Duration(conf.Get().Auth.SessionExpiryInHours)),
 	}
-	if err = db.Get().Create(&session).Error; err != nil {
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&session).Error; err != nil {
 		return err
 	}
 
 	sess := kit.GetSession(userSessionName)
 	sess.Values["sessionToken"] = session.Token
-	sess.Save(kit.Request, kit.Response)
-	redirectURL := kit.Getenv("SUPERKIT_AUTH_REDIRECT_AFTER_LOGIN", "/profile")
-
-	return kit.Redirect(http.StatusSeeOther, redirectURL)
+	return sess.Save(kit.Request, kit.Response)
 }
 
 func HandleLoginDelete(kit *kit.Kit) error {
@@ -91,7 +124,7 @@ func HandleLoginDelete(kit *kit.Kit) error {
 		sess.Values = map[any]any{}
 		sess.Save(kit.Request, kit.Response)
 	}()
-	err := db.Get().Delete(&Session{}, "token = ?", sess.Values["sessionToken"]).Error
+	err := db.Get().WithContext(kit.Request.Context()).Delete(&Session{}, "token = ?", sess.Values["sessionToken"]).Error
 	if err != nil {
 		return err
 	}
@@ -106,8 +139,8 @@ func HandleEmailVerify(kit *kit.Kit) error {
 
 	token, err := jwt.ParseWithClaims(
 		tokenStr, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
-			return []byte(os.Getenv("SUPERKIT_SECRET")), nil
-		}, jwt.WithLeeway(5*time.Second))
+			return []byte(conf.Get().Secret), nil
+		}, jwt.WithLeeway(5*time.Second), jwt.WithAudience(emailVerificationAudience))
 	if err != nil {
 		return kit.Render(EmailVerificationError("invalid verification token"))
 	}
@@ -129,7 +162,7 @@ func HandleEmailVerify(kit *kit.Kit) error {
 	}
 
 	var user User
-	err = db.Get().First(&user, userID).Error
+	err = db.Get().WithContext(kit.Request.Context()).First(&user, userID).Error
 	if err != nil {
 		return err
 	}
@@ -140,7 +173,7 @@ func HandleEmailVerify(kit *kit.Kit) error {
 
 	now := sql.NullTime{Time: time.Now(), Valid: true}
 	user.EmailVerifiedAt = now
-	err = db.Get().Save(&user).Error
+	err = db.Get().WithContext(kit.Request.Context()).Save(&user).Error
 	if err != nil {
 		return err
 	}
@@ -157,16 +190,19 @@ func AuthenticateUser(kit *kit.Kit) (kit.Auth, error) {
 	}
 
 	var session Session
-	err := db.Get().
+	err := db.Get().WithContext(kit.Request.Context()).
 		Preload("User").
 		Find(&session, "token = ? AND expires_at > ?", token, time.Now()).Error
-	if err != nil || session.ID == 0 {
+	if err != nil || session.ID == 0 || session.User.Disabled() {
 		return auth, nil
 	}
 
+	logging.Add(kit.Request.Context(), "user_id", session.User.ID)
 	return Auth{
-		LoggedIn: true,
-		UserID:   session.User.ID,
-		Email:    session.User.Email,
+		LoggedIn:       true,
+		UserID:         session.User.ID,
+		Email:          session.User.Email,
+		Role:           session.User.Role,
+		ImpersonatorID: session.ImpersonatorID,
 	}, nil
 }
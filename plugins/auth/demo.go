@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"strings"
+
+	"smartquiz/app/conf"
+)
+
+// demoAccountMessage is shown for the changes the demo account refuses.
+const demoAccountMessage = "This can't be changed on the demo account."
+
+// isDemoAccount reports whether the email address is the one of the
+// account shared by the visitors in demo mode. A visitor must not lock
+// the others out, so its password, second factor, API tokens and
+// deletion can't be changed.
+func isDemoAccount(email string) bool {
+	demo := conf.Get().Demo
+	return demo.Enabled && strings.EqualFold(email, demo.Email)
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/dbtest"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+func TestDemoAccount(t *testing.T) {
+	database := dbtest.New(t)
+	previous := conf.Get()
+	c := *previous
+	c.Demo.Enabled = true
+	c.Demo.Email = "demo@example.com"
+	conf.Set(&c)
+	t.Cleanup(func() { conf.Set(previous) })
+
+	createToken := func(user User) int64 {
+		form := url.Values{"name": {"script"}, "scopeRead": {"true"}}
+		r := httptest.NewRequest(http.MethodPost, "/profile/tokens", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		auth := Auth{UserID: user.ID, Email: user.Email, LoggedIn: true}
+		r = r.WithContext(context.WithValue(r.Context(), kit.AuthKey{}, auth))
+		if err := HandleTokenCreate(&kit.Kit{Response: httptest.NewRecorder(), Request: r}); err != nil {
+			t.Fatal(err)
+		}
+		var count int64
+		database.Model(&APIToken{}).Where("user_id = ?", user.ID).Count(&count)
+		return count
+	}
+	demo := User{Email: "Demo@example.com", PasswordHash: "x"}
+	visitor := User{Email: "visitor@example.com", PasswordHash: "x"}
+	database.Create(&demo)
+	database.Create(&visitor)
+	if n := createToken(demo); n != 0 {
+		t.Errorf("expected the demo account to refuse API tokens, got %d", n)
+	}
+	if n := createToken(visitor); n != 1 {
+		t.Errorf("expected other accounts to get API tokens, got %d", n)
+	}
+
+	c.Demo.Enabled = false
+	if isDemoAccount(demo.Email) {
+		t.Error("expected no demo account without the demo mode")
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/google/uuid"
+)
+
+// Impersonation sessions expire quickly, support rarely needs more.
+const impersonationExpiry = time.Hour
+
+// Impersonate logs the admin in as the given user. The session of the
+// admin is kept and restored by HandleImpersonationStop.
+func Impersonate(kit *kit.Kit, user User) error {
+	admin, ok := kit.Auth().(Auth)
+	if !ok || !admin.IsAdmin() || admin.Impersonating() {
+		return errors.New("only admins can impersonate users")
+	}
+	if user.IsAdmin() || user.Disabled() {
+		return errors.New("admins and disabled users can't be impersonated")
+	}
+
+	session := Session{
+		UserID:         user.ID,
+		Token:          uuid.New().String(),
+		IPAddress:      clientIP(kit.Request),
+		UserAgent:      kit.Request.UserAgent(),
+		ExpiresAt:      time.Now().Add(impersonationExpiry),
+		ImpersonatorID: admin.UserID,
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&session).Error; err != nil {
+		return err
+	}
+	slog.InfoContext(kit.Request.Context(), "admin impersonates user", "admin", admin.UserID, "user", user.ID)
+
+	sess := kit.GetSession(userSessionName)
+	sess.Values["impersonatorToken"] = sess.Values["sessionToken"]
+	sess.Values["sessionToken"] = session.Token
+	return sess.Save(kit.Request, kit.Response)
+}
+
+// HandleImpersonationStop ends the impersonation and logs the admin back
+// in with their own session.
+func HandleImpersonationStop(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	if !auth.Impersonating() {
+		return kit.Redirect(http.StatusSeeOther, "/profile")
+	}
+
+	sess := kit.GetSession(userSessionName)
+	err := db.Get().WithContext(kit.Request.Context()).Delete(&Session{}, "token = ?", sess.Values["sessionToken"]).Error
+	if err != nil {
+		return err
+	}
+	sess.Values["sessionToken"] = sess.Values["impersonatorToken"]
+	delete(sess.Values, "impersonatorToken")
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+	slog.InfoContext(kit.Request.Context(), "admin stopped impersonating user", "admin", auth.ImpersonatorID, "user", auth.UserID)
+	return kit.Redirect(http.StatusSeeOther, "/admin/users")
+}
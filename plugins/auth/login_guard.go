@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/pkg/ratelimit"
+
+	"github.com/anthdm/superkit/kit"
+)
+
+const (
+	maxAccountLockout = time.Hour
+	// Failed attempts older than this no longer count towards a lockout.
+	failedLoginWindow = 24 * time.Hour
+)
+
+// loginGuard protects the login against brute force attacks. Every IP
+// address has a token bucket of login attempts, and accounts are locked
+// for an exponentially growing duration once too many wrong passwords
+// or 2FA codes were entered in a row.
+type loginGuard struct {
+	mu          sync.Mutex
+	ipLimiter   *ratelimit.Limiter
+	failures    map[string]*accountFailures
+	maxFailures int
+	lockout     time.Duration
+	lastPrune   time.Time
+	now         func() time.Time
+}
+
+type accountFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+var (
+	defaultGuard *loginGuard
+	guardOnce    sync.Once
+	// resendLimiter limits how often a verification email can be requested.
+	resendLimiter = ratelimit.New(3, 10*time.Minute)
+)
+
+func getLoginGuard() *loginGuard {
+	guardOnce.Do(func() {
+		defaultGuard = newLoginGuard(conf.Get().Auth, time.Now)
+	})
+	return defaultGuard
+}
+
+// newLoginGuard returns a guard with the limits of the config that
+// reads the time from now.
+func newLoginGuard(config conf.Auth, now func() time.Time) *loginGuard {
+	return &loginGuard{
+		ipLimiter:   ratelimit.NewWithClock(config.LoginAttemptsPerMinute, time.Minute, now),
+		failures:    make(map[string]*accountFailures),
+		maxFailures: config.MaxFailedLogins,
+		lockout:     time.Duration(config.LockoutInSeconds) * time.Second,
+		lastPrune:   now(),
+		now:         now,
+	}
+}
+
+// check returns how long the client has to wait before it may try to
+// log in to the given account again, or 0 if it may try now.
+func (g *loginGuard) check(r *http.Request, email string) time.Duration {
+	if ok, wait := g.ipLimiter.Allow(clientIP(r)); !ok {
+		return wait
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	if f, ok := g.failures[normalizeEmail(email)]; ok && now.Before(f.lockedUntil) {
+		return f.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail records a failed attempt in the audit log and locks the account
+// once it reached the maximum number of failures. Each further failure
+// doubles the lockout.
+func (g *loginGuard) fail(r *http.Request, email string, reason string) {
+	now := g.now()
+	key := normalizeEmail(email)
+
+	g.mu.Lock()
+	g.prune(now)
+	f, ok := g.failures[key]
+	if !ok {
+		f = &accountFailures{}
+		g.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= g.maxFailures {
+		exp := math.Pow(2, float64(f.count-g.maxFailures))
+		lockout := time.Duration(math.Min(float64(g.lockout)*exp, float64(maxAccountLockout)))
+		f.lockedUntil = now.Add(lockout)
+	}
+	count := f.count
+	g.mu.Unlock()
+
+	slog.WarnContext(r.Context(), "failed login attempt",
+		"email", email,
+		"ip", clientIP(r),
+		"reason", reason,
+		"failures", count,
+	)
+	attempt := LoginAttempt{
+		Email:     email,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Reason:    reason,
+	}
+	// Not cancelled with the request, a client dropping the connection
+	// must not keep its attempts out of the audit log.
+	if err := db.Get().WithContext(context.WithoutCancel(r.Context())).Create(&attempt).Error; err != nil {
+		slog.ErrorContext(r.Context(), "failed to store login attempt", "err", err)
+	}
+}
+
+// succeed forgets the failed attempts of the account.
+func (g *loginGuard) succeed(email string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, normalizeEmail(email))
+}
+
+func (g *loginGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Hour {
+		return
+	}
+	g.lastPrune = now
+	for key, f := range g.failures {
+		if now.Sub(f.last) > failedLoginWindow && now.After(f.lockedUntil) {
+			delete(g.failures, key)
+		}
+	}
+}
+
+func tooManyAttemptsMessage(wait time.Duration) string {
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return fmt.Sprintf("too many login attempts, try again in %s", wait.Round(time.Second))
+}
+
+// RateLimitKey identifies the client of a request for rate limiting:
+// the logged in user if there is one, otherwise the IP address. It must
+// run after the authentication middleware.
+func RateLimitKey(r *http.Request) string {
+	if auth, ok := r.Context().Value(kit.AuthKey{}).(Auth); ok && auth.Check() {
+		return fmt.Sprintf("user:%d", auth.UserID)
+	}
+	return "ip:" + clientIP(r)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
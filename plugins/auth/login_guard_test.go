@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"cmp"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/dbtest"
+
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginGuard(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		// action is "check", "fail" or "succeed".
+		action string
+		ip     string
+		email  string
+		// wait is the expected result of check.
+		wait time.Duration
+	}
+	check := func(wait time.Duration) step { return step{action: "check", wait: wait} }
+	fail := step{action: "fail"}
+	accountConfig := conf.Auth{LoginAttemptsPerMinute: 100, MaxFailedLogins: 3, LockoutInSeconds: 60}
+
+	tests := []struct {
+		name   string
+		config conf.Auth
+		steps  []step
+	}{
+		{
+			name:   "per IP limit",
+			config: conf.Auth{LoginAttemptsPerMinute: 3, MaxFailedLogins: 100, LockoutInSeconds: 60},
+			steps: []step{
+				check(0), check(0), check(0),
+				check(20 * time.Second),
+				{action: "check", ip: "192.0.2.2"},
+				{advance: 10 * time.Second, action: "check", wait: 10 * time.Second},
+				{advance: 10 * time.Second, action: "check"},
+				check(20 * time.Second),
+			},
+		},
+		{
+			name:   "lockout after the maximum failures",
+			config: accountConfig,
+			steps: []step{
+				fail, fail, check(0),
+				fail, check(time.Minute),
+				{action: "check", ip: "192.0.2.2", wait: time.Minute},
+				{advance: 40 * time.Second, action: "check", wait: 20 * time.Second},
+				{advance: 20 * time.Second, action: "check"},
+			},
+		},
+		{
+			name:   "backoff doubles",
+			config: accountConfig,
+			steps: []step{
+				fail, fail, fail, check(time.Minute),
+				fail, check(2 * time.Minute),
+				fail, check(4 * time.Minute),
+				fail, fail, fail, fail, fail, fail, check(maxAccountLockout),
+			},
+		},
+		{
+			name:   "failures are counted after a lockout",
+			config: accountConfig,
+			steps: []step{
+				fail, fail, fail,
+				{advance: time.Minute, action: "check"},
+				fail, check(2 * time.Minute),
+			},
+		},
+		{
+			name:   "success resets",
+			config: accountConfig,
+			steps: []step{
+				fail, fail, fail, check(time.Minute),
+				{action: "succeed"}, check(0),
+				fail, fail, check(0),
+				fail, check(time.Minute),
+			},
+		},
+		{
+			name:   "accounts are separate",
+			config: accountConfig,
+			steps: []step{
+				fail, fail, fail,
+				{action: "check", email: "bob@example.com"},
+				{action: "check", email: " Alice@Example.com", wait: time.Minute},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.New(t)
+			now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+			guard := newLoginGuard(tt.config, func() time.Time { return now })
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				r := httptest.NewRequest(http.MethodPost, "/login", nil)
+				r.RemoteAddr = cmp.Or(s.ip, "192.0.2.1") + ":1234"
+				email := cmp.Or(s.email, "alice@example.com")
+				switch s.action {
+				case "check":
+					// The limiter computes the wait from a rate, which
+					// isn't exact.
+					if got := guard.check(r, email).Round(time.Millisecond); got != s.wait {
+						t.Errorf("step %d: got wait %s, want %s", i+1, got, s.wait)
+					}
+				case "fail":
+					guard.fail(r, email, "invalid credentials")
+				case "succeed":
+					guard.succeed(email)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginGuardAuditLog(t *testing.T) {
+	database := dbtest.New(t)
+	guard := newLoginGuard(conf.Auth{LoginAttemptsPerMinute: 10, MaxFailedLogins: 3, LockoutInSeconds: 60}, time.Now)
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = "192.0.2.7:1234"
+	r.Header.Set("User-Agent", "curl/8.0")
+	guard.fail(r, "alice@example.com", "invalid credentials")
+	guard.fail(r, "alice@example.com", "invalid two-factor code")
+	guard.succeed("alice@example.com")
+
+	var attempts []LoginAttempt
+	if err := database.Order("id").Find(&attempts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("got %d login attempts, want 2", len(attempts))
+	}
+	for i, reason := range []string{"invalid credentials", "invalid two-factor code"} {
+		a := attempts[i]
+		if a.Email != "alice@example.com" || a.IPAddress != "192.0.2.7" || a.UserAgent != "curl/8.0" || a.Reason != reason {
+			t.Errorf("unexpected login attempt %+v", a)
+		}
+	}
+}
+
+// TestLoginGuardGatesLogin checks that a locked account can't log in
+// with the right password, and can't finish a login with the right
+// second factor.
+func TestLoginGuardGatesLogin(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	getLoginGuard()
+	previous := defaultGuard
+	t.Cleanup(func() { defaultGuard = previous })
+	defaultGuard = newLoginGuard(conf.Auth{LoginAttemptsPerMinute: 100, MaxFailedLogins: 2, LockoutInSeconds: 60}, time.Now)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("Passw0rd!"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := sql.NullTime{Time: time.Now(), Valid: true}
+	user := User{Email: "alice@example.com", PasswordHash: string(hash), EmailVerifiedAt: verified}
+	database.Create(&user)
+	login := func(password string) string {
+		form := url.Values{"email": {user.Email}, "password": {password}}
+		return newBrowser(t).do(HandleLoginCreate, http.MethodPost, "/login", form).Body.String()
+	}
+	sessions := func(user User) int64 {
+		var count int64
+		database.Model(&Session{}).Where("user_id = ?", user.ID).Count(&count)
+		return count
+	}
+
+	login("wrong")
+	login("wrong")
+	if body := login("Passw0rd!"); !strings.Contains(body, "too many login attempts") {
+		t.Errorf("expected the password login to be locked, got %s", body)
+	}
+	if sessions(user) != 0 {
+		t.Error("expected no session for a locked account")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: "bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := User{
+		Email:           "bob@example.com",
+		PasswordHash:    string(hash),
+		EmailVerifiedAt: verified,
+		TOTPSecret:      key.Secret(),
+		TOTPEnabledAt:   verified,
+	}
+	database.Create(&bob)
+	b := newBrowser(t)
+	b.do(HandleLoginCreate, http.MethodPost, "/login", url.Values{"email": {bob.Email}, "password": {"Passw0rd!"}})
+	b.do(HandleTwoFactorLoginCreate, http.MethodPost, "/login/2fa", url.Values{"code": {"000000"}})
+	b.do(HandleTwoFactorLoginCreate, http.MethodPost, "/login/2fa", url.Values{"code": {"000000"}})
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := b.do(HandleTwoFactorLoginCreate, http.MethodPost, "/login/2fa", url.Values{"code": {code}}).Body.String()
+	if !strings.Contains(body, "too many login attempts") {
+		t.Errorf("expected the two-factor step to be locked, got %s", body)
+	}
+	if sessions(bob) != 0 {
+		t.Error("expected no session for a locked account")
+	}
+}
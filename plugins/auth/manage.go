@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"smartquiz/app/db"
+
+	v "github.com/anthdm/superkit/validate"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Account management shared by the admin pages and the smartquiz
+// command.
+
+// CreateUser creates a user with the rules of the signup form. Unlike a
+// signup no verification email is sent.
+func CreateUser(ctx context.Context, values SignupFormValues, role string) (User, error) {
+	return CreateUserTx(db.Get().WithContext(ctx), values, role)
+}
+
+// CreateUserTx is CreateUser in the transaction tx.
+func CreateUserTx(tx *gorm.DB, values SignupFormValues, role string) (User, error) {
+	if errors, ok := v.Validate(values, signupSchema); !ok {
+		return User{}, validationError(errors)
+	}
+	if role != RoleUser && role != RoleAdmin {
+		return User{}, fmt.Errorf("invalid role %q", role)
+	}
+	var count int64
+	if err := tx.Model(&User{}).Where("email = ?", values.Email).Count(&count).Error; err != nil {
+		return User{}, err
+	}
+	if count > 0 {
+		return User{}, fmt.Errorf("a user with the email %s already exists", values.Email)
+	}
+	user, err := createUserFromFormValues(tx, values)
+	if err != nil {
+		return user, err
+	}
+	if role != RoleUser {
+		user.Role = role
+		err = tx.Model(&user).Update("role", role).Error
+	}
+	return user, err
+}
+
+// SetPassword replaces the password of the user and logs them out on
+// all devices, someone who knew the old password might still be logged
+// in.
+func SetPassword(ctx context.Context, user *User, password string) error {
+	values := struct {
+		Password string `form:"password"`
+	}{password}
+	if errors, ok := v.Validate(values, v.Schema{"password": signupSchema["password"]}); !ok {
+		return validationError(errors)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	if err := db.Get().WithContext(ctx).Model(user).Update("password_hash", user.PasswordHash).Error; err != nil {
+		return err
+	}
+	return db.Get().WithContext(ctx).Delete(&Session{}, "user_id = ?", user.ID).Error
+}
+
+// VerifyEmail marks the email address of the user as verified.
+func VerifyEmail(ctx context.Context, user *User) error {
+	user.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return db.Get().WithContext(ctx).Model(user).Update("email_verified_at", user.EmailVerifiedAt).Error
+}
+
+// DisableUser disables the account and logs the user out on all
+// devices.
+func DisableUser(ctx context.Context, user *User) error {
+	user.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := db.Get().WithContext(ctx).Model(user).Update("disabled_at", user.DisabledAt).Error; err != nil {
+		return err
+	}
+	return db.Get().WithContext(ctx).Delete(&Session{}, "user_id = ?", user.ID).Error
+}
+
+func EnableUser(ctx context.Context, user *User) error {
+	user.DisabledAt = sql.NullTime{}
+	return db.Get().WithContext(ctx).Model(user).Update("disabled_at", user.DisabledAt).Error
+}
+
+func validationError(errors v.Errors) error {
+	var msgs []string
+	for field, list := range errors {
+		msgs = append(msgs, field+": "+strings.Join(list, ", "))
+	}
+	sort.Strings(msgs)
+	return fmt.Errorf("invalid values: %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCreateUser(t *testing.T) {
+	dbtest.New(t)
+	values := SignupFormValues{
+		Email:     "anna@example.com",
+		FirstName: "Anna",
+		LastName:  "Berg",
+		Password:  "Secret!pass1",
+	}
+	user, err := CreateUser(context.Background(), values, RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsAdmin() {
+		t.Error("expected an admin")
+	}
+
+	if _, err := CreateUser(context.Background(), values, RoleUser); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected a duplicate email to fail, got %v", err)
+	}
+	values.Email = "carl@example.com"
+	values.Password = "short"
+	if _, err := CreateUser(context.Background(), values, RoleUser); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected a weak password to fail, got %v", err)
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	database := dbtest.New(t)
+	user := User{Email: "anna@example.com", PasswordHash: "x"}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	session := Session{UserID: user.ID, Token: "token", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := database.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetPassword(context.Background(), &user, "New!pass12"); err != nil {
+		t.Fatal(err)
+	}
+	var stored User
+	database.First(&stored, user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("New!pass12")) != nil {
+		t.Error("expected the new password to be stored")
+	}
+	var count int64
+	database.Model(&Session{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Error("expected the sessions of the user to be deleted")
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"smartquiz/app/conf"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig configures the OpenID Connect login. The login is disabled
+// when no issuer is configured.
+type OIDCConfig struct {
+	// Name of the identity provider shown on the login button.
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+func oidcConfigFromConf() OIDCConfig {
+	c := conf.Get().Auth
+	return OIDCConfig{
+		Name:         c.OIDCName,
+		Issuer:       c.OIDCIssuer,
+		ClientID:     c.OIDCClientID,
+		ClientSecret: c.OIDCClientSecret,
+		RedirectURL:  c.OIDCRedirectURL,
+	}
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return len(c.Issuer) > 0
+}
+
+// oidcProvider runs the authorization code flow with PKCE against an
+// OpenID Connect identity provider.
+type oidcProvider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcIdentity holds the claims of a verified ID token.
+type oidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	// AuthTime is when the user last authenticated at the provider, zero
+	// if the provider didn't tell.
+	AuthTime time.Time
+}
+
+func newOIDCProvider(ctx context.Context, config OIDCConfig) (*oidcProvider, error) {
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	return &oidcProvider{
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcInstance *oidcProvider
+)
+
+// getOIDCProvider runs the discovery on first use. A failed discovery is
+// retried on the next login instead of disabling the login until restart.
+func getOIDCProvider(ctx context.Context) (*oidcProvider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcInstance != nil {
+		return oidcInstance, nil
+	}
+	config := oidcConfigFromConf()
+	if !config.Enabled() {
+		return nil, errors.New("oidc login is not configured")
+	}
+	provider, err := newOIDCProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	oidcInstance = provider
+	return provider, nil
+}
+
+func (p *oidcProvider) authCodeURL(state, nonce, verifier string, opts ...oauth2.AuthCodeOption) string {
+	opts = append([]oauth2.AuthCodeOption{oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)}, opts...)
+	return p.oauth2.AuthCodeURL(state, opts...)
+}
+
+// reauthenticate makes the provider ask for the credentials of the user
+// even if they are still logged in there.
+var reauthenticate = []oauth2.AuthCodeOption{
+	oauth2.SetAuthURLParam("prompt", "login"),
+	oauth2.SetAuthURLParam("max_age", "0"),
+}
+
+// exchange redeems the authorization code and verifies the returned ID
+// token, including its signature, audience, expiry and nonce.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier, nonce string) (oidcIdentity, error) {
+	var identity oidcIdentity
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return identity, fmt.Errorf("oidc code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return identity, errors.New("oidc token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return identity, fmt.Errorf("invalid oidc id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return identity, errors.New("invalid oidc id token nonce")
+	}
+
+	var claims struct {
+		Email         string    `json:"email"`
+		EmailVerified boolClaim `json:"email_verified"`
+		GivenName     string    `json:"given_name"`
+		FamilyName    string    `json:"family_name"`
+		AuthTime      int64     `json:"auth_time"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return identity, err
+	}
+	return oidcIdentity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		AuthTime:      unixTime(claims.AuthTime),
+	}, nil
+}
+
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// boolClaim accepts both true and "true", some providers send
+// email_verified as a string.
+type boolClaim bool
+
+func (b *boolClaim) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = boolClaim(v)
+	case string:
+		*b = boolClaim(v == "true")
+	}
+	return nil
+}
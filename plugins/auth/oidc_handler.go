@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcSessionName  = "oidc-login"
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+// Purposes of a round trip to the identity provider.
+const (
+	oidcLogin = "login"
+	// oidcDeleteAccount confirms the deletion of the account of a user
+	// without a password of their own.
+	oidcDeleteAccount = "delete"
+)
+
+// HandleOIDCLogin redirects to the identity provider. The state, nonce
+// and PKCE verifier are kept in a cookie session until the callback.
+func HandleOIDCLogin(kit *kit.Kit) error {
+	return startOIDC(kit, oidcLogin, 0)
+}
+
+func startOIDC(kit *kit.Kit, purpose string, userID uint, opts ...oauth2.AuthCodeOption) error {
+	provider, err := getOIDCProvider(kit.Request.Context())
+	if err != nil {
+		slog.ErrorContext(kit.Request.Context(), "oidc login unavailable", "err", err)
+		return renderOIDCError(kit, "single sign-on is currently unavailable")
+	}
+
+	state, nonce := randomString(), randomString()
+	verifier := oauth2.GenerateVerifier()
+
+	sess := kit.GetSession(oidcSessionName)
+	sess.Values["state"] = state
+	sess.Values["nonce"] = nonce
+	sess.Values["verifier"] = verifier
+	sess.Values["expiresAt"] = time.Now().Add(oidcLoginTimeout).Unix()
+	sess.Values["purpose"] = purpose
+	sess.Values["userID"] = userID
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+	return kit.Redirect(http.StatusSeeOther, provider.authCodeURL(state, nonce, verifier, opts...))
+}
+
+// HandleOIDCCallback finishes the login started by HandleOIDCLogin. The
+// user is looked up by the subject of the ID token, or linked by its
+// verified email address, and created if there is none.
+func HandleOIDCCallback(kit *kit.Kit) error {
+	sess := kit.GetSession(oidcSessionName)
+	state, _ := sess.Values["state"].(string)
+	nonce, _ := sess.Values["nonce"].(string)
+	verifier, _ := sess.Values["verifier"].(string)
+	expiresAt, _ := sess.Values["expiresAt"].(int64)
+	purpose, _ := sess.Values["purpose"].(string)
+	userID, _ := sess.Values["userID"].(uint)
+	sess.Values = map[any]any{}
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+
+	query := kit.Request.URL.Query()
+	if errCode := query.Get("error"); len(errCode) > 0 {
+		slog.WarnContext(kit.Request.Context(), "oidc login failed", "error", errCode, "description", query.Get("error_description"))
+		return renderOIDCError(kit, "single sign-on failed, please try again")
+	}
+	if len(state) == 0 || time.Now().Unix() > expiresAt ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		return renderOIDCError(kit, "your login expired, please try again")
+	}
+
+	provider, err := getOIDCProvider(kit.Request.Context())
+	if err != nil {
+		slog.ErrorContext(kit.Request.Context(), "oidc login unavailable", "err", err)
+		return renderOIDCError(kit, "single sign-on is currently unavailable")
+	}
+	identity, err := provider.exchange(kit.Request.Context(), query.Get("code"), verifier, nonce)
+	if err != nil {
+		slog.WarnContext(kit.Request.Context(), "oidc login failed", "err", err)
+		return renderOIDCError(kit, "single sign-on failed, please try again")
+	}
+	if purpose == oidcDeleteAccount {
+		return confirmOIDCAccountDelete(kit, userID, identity)
+	}
+	if len(identity.Email) == 0 || !identity.EmailVerified {
+		return renderOIDCError(kit, "your account at the identity provider has no verified email address")
+	}
+
+	user, err := findOrCreateOIDCUser(kit.Request.Context(), identity)
+	if err != nil {
+		if errors.Is(err, errOIDCAccountLinked) {
+			return renderOIDCError(kit, err.Error())
+		}
+		return err
+	}
+	if user.Disabled() {
+		return renderOIDCError(kit, accountDisabledMessage)
+	}
+
+	if user.TwoFactorEnabled() {
+		if err := startTwoFactorLogin(kit, user); err != nil {
+			return err
+		}
+		return kit.Render(LoginIndex(LoginIndexPageData{TwoFactor: true}))
+	}
+
+	getLoginGuard().succeed(user.Email)
+	if err := createSession(kit, user); err != nil {
+		return err
+	}
+	redirectURL := conf.Get().Auth.RedirectAfterLogin
+	return kit.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+var errOIDCAccountLinked = errors.New("this email address is already linked to another single sign-on account")
+
+func findOrCreateOIDCUser(ctx context.Context, identity oidcIdentity) (User, error) {
+	var user User
+	err := db.Get().WithContext(ctx).First(&user, "oidc_subject = ?", identity.Subject).Error
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	err = db.Get().WithContext(ctx).First(&user, "email = ?", identity.Email).Error
+	if err == nil {
+		if len(user.OIDCSubject) > 0 {
+			return user, errOIDCAccountLinked
+		}
+		user.OIDCSubject = identity.Subject
+		// The identity provider verified the address.
+		if !user.EmailVerifiedAt.Valid {
+			user.EmailVerifiedAt = now
+		}
+		return user, db.Get().WithContext(ctx).Save(&user).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// Users created by single sign-on have no password of their own. They
+	// can set one with the forgot password flow.
+	hash, err := bcrypt.GenerateFromPassword([]byte(randomString()), bcrypt.DefaultCost)
+	if err != nil {
+		return user, err
+	}
+	user = User{
+		Email:           identity.Email,
+		FirstName:       identity.GivenName,
+		LastName:        identity.FamilyName,
+		PasswordHash:    string(hash),
+		EmailVerifiedAt: now,
+		OIDCSubject:     identity.Subject,
+		Role:            RoleUser,
+	}
+	return user, db.Get().WithContext(ctx).Create(&user).Error
+}
+
+func renderOIDCError(kit *kit.Kit, msg string) error {
+	errors := v.Errors{}
+	errors.Add("credentials", msg)
+	return kit.Render(LoginIndex(LoginIndexPageData{FormErrors: errors}))
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
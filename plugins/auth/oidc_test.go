@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider. It issues a
+// signed ID token for every authorization code whose PKCE verifier
+// matches the challenge registered with authorize.
+type mockOIDCServer struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	claims     map[string]any
+	challenges map[string]string // code -> code challenge
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &mockOIDCServer{key: key, challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                s.URL,
+			"authorization_endpoint":                s.URL + "/authorize",
+			"token_endpoint":                        s.URL + "/token",
+			"jwks_uri":                              s.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		challenge, ok := s.challenges[r.Form.Get("code")]
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     s.sign(t),
+		})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// authorize registers the code challenge of the authorization URL for
+// code and returns the nonce of the request.
+func (s *mockOIDCServer) authorize(t *testing.T, authURL, code string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 code challenge, got %q", q.Get("code_challenge_method"))
+	}
+	s.challenges[code] = q.Get("code_challenge")
+	return q.Get("nonce")
+}
+
+func (s *mockOIDCServer) sign(t *testing.T) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: s.key},
+		(&jose.SignerOptions{}).WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := json.Marshal(s.claims)
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestOIDCExchange(t *testing.T) {
+	server := newMockOIDCServer(t)
+	ctx := context.Background()
+	provider, err := newOIDCProvider(ctx, OIDCConfig{
+		Issuer:      server.URL,
+		ClientID:    "smartquiz",
+		RedirectURL: "http://localhost:7331/login/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const verifier = "verifier-0123456789-0123456789-0123456789"
+	claims := func(nonce string) map[string]any {
+		return map[string]any{
+			"iss":            server.URL,
+			"aud":            "smartquiz",
+			"sub":            "user-1",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          "anna@example.com",
+			"email_verified": "true",
+			"given_name":     "Anna",
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		nonce := server.authorize(t, provider.authCodeURL("state", "nonce-1", verifier), "code-1")
+		server.claims = claims(nonce)
+		identity, err := provider.exchange(ctx, "code-1", verifier, "nonce-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.Subject != "user-1" || identity.Email != "anna@example.com" || !identity.EmailVerified || identity.GivenName != "Anna" {
+			t.Fatalf("unexpected identity %+v", identity)
+		}
+	})
+
+	t.Run("reauthenticate", func(t *testing.T) {
+		authURL := provider.authCodeURL("state", "nonce-5", verifier, reauthenticate...)
+		if u, _ := url.Parse(authURL); u.Query().Get("prompt") != "login" || u.Query().Get("max_age") != "0" {
+			t.Fatalf("expected the provider to be asked for a new login, got %s", authURL)
+		}
+		nonce := server.authorize(t, authURL, "code-5")
+		authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		server.claims = claims(nonce)
+		server.claims["auth_time"] = authTime.Unix()
+		identity, err := provider.exchange(ctx, "code-5", verifier, "nonce-5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !identity.AuthTime.Equal(authTime) {
+			t.Fatalf("expected the auth time %s, got %s", authTime, identity.AuthTime)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		nonce := server.authorize(t, provider.authCodeURL("state", "nonce-2", verifier), "code-2")
+		server.claims = claims(nonce)
+		_, err := provider.exchange(ctx, "code-2", "another-verifier-0123456789-0123456789", "nonce-2")
+		if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+			t.Fatalf("expected invalid_grant, got %v", err)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		server.authorize(t, provider.authCodeURL("state", "nonce-3", verifier), "code-3")
+		server.claims = claims("replayed")
+		if _, err := provider.exchange(ctx, "code-3", verifier, "nonce-3"); err == nil {
+			t.Fatal("expected a nonce mismatch to be rejected")
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		nonce := server.authorize(t, provider.authCodeURL("state", "nonce-4", verifier), "code-4")
+		server.claims = claims(nonce)
+		server.claims["aud"] = "another-client"
+		if _, err := provider.exchange(ctx, "code-4", verifier, "nonce-4"); err == nil {
+			t.Fatal("expected a token for another client to be rejected")
+		}
+	})
+}
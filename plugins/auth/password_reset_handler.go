@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+	"smartquiz/pkg/ratelimit"
+	"smartquiz/pkg/tracing"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const passwordResetAudience = "password-reset"
+
+// Password reset emails are limited per address so the form can't be
+// used to flood a mailbox, and per IP address against scripts trying
+// many addresses.
+var (
+	resetEmailLimiter = ratelimit.New(3, time.Hour)
+	resetIPLimiter    = ratelimit.New(10, time.Hour)
+)
+
+var forgotPasswordSchema = v.Schema{
+	"email": v.Rules(v.Email),
+}
+
+var resetPasswordSchema = v.Schema{
+	"password": signupSchema["password"],
+}
+
+type ForgotPasswordFormValues struct {
+	Email string `form:"email"`
+	Sent  bool
+}
+
+type ResetPasswordFormValues struct {
+	Token           string `form:"token"`
+	Password        string `form:"password"`
+	PasswordConfirm string `form:"passwordConfirm"`
+}
+
+func HandleForgotPasswordIndex(kit *kit.Kit) error {
+	return kit.Render(ForgotPasswordIndex(ForgotPasswordFormValues{}))
+}
+
+func HandleForgotPasswordCreate(kit *kit.Kit) error {
+	var values ForgotPasswordFormValues
+	errors, ok := v.Request(kit.Request, &values, forgotPasswordSchema)
+	if !ok {
+		return kit.Render(ForgotPasswordForm(values, errors))
+	}
+
+	// Limited before the lookup, so the limits apply the same way to
+	// addresses with and without an account.
+	ipOK, ipWait := resetIPLimiter.Allow(clientIP(kit.Request))
+	emailOK, emailWait := resetEmailLimiter.Allow(strings.ToLower(values.Email))
+	if !ipOK || !emailOK {
+		wait := max(ipWait, emailWait).Round(time.Second)
+		errors.Add("email", fmt.Sprintf("too many password reset requests, try again in %s", wait))
+		return kit.Render(ForgotPasswordForm(values, errors))
+	}
+
+	// Always respond with the same message so the form can't be used
+	// to find out which email addresses have an account.
+	values.Sent = true
+
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).Find(&user, "email = ?", values.Email).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return kit.Render(ForgotPasswordForm(values, v.Errors{}))
+	}
+	if isDemoAccount(user.Email) {
+		values.Sent = false
+		errors.Add("email", demoAccountMessage)
+		return kit.Render(ForgotPasswordForm(values, errors))
+	}
+
+	token, err := createPasswordResetToken(user)
+	if err != nil {
+		return err
+	}
+	tracing.Emit(kit.Request.Context(), PasswordResetEvent, UserWithResetToken{
+		User:  user,
+		Token: token,
+	})
+
+	return kit.Render(ForgotPasswordForm(values, v.Errors{}))
+}
+
+func HandleResetPasswordIndex(kit *kit.Kit) error {
+	tokenStr := kit.Request.URL.Query().Get("token")
+	if _, err := userFromPasswordResetToken(kit.Request.Context(), tokenStr); err != nil {
+		return kit.Render(EmailVerificationError(err.Error()))
+	}
+	return kit.Render(ResetPasswordIndex(ResetPasswordFormValues{Token: tokenStr}, v.Errors{}))
+}
+
+func HandleResetPasswordCreate(kit *kit.Kit) error {
+	var values ResetPasswordFormValues
+	errors, ok := v.Request(kit.Request, &values, resetPasswordSchema)
+	if !ok {
+		return kit.Render(ResetPasswordForm(values, errors))
+	}
+	if values.Password != values.PasswordConfirm {
+		errors.Add("passwordConfirm", "passwords do not match")
+		return kit.Render(ResetPasswordForm(values, errors))
+	}
+
+	user, err := userFromPasswordResetToken(kit.Request.Context(), values.Token)
+	if err != nil {
+		errors.Add("token", err.Error())
+		return kit.Render(ResetPasswordForm(values, errors))
+	}
+	if isDemoAccount(user.Email) {
+		errors.Add("password", demoAccountMessage)
+		return kit.Render(ResetPasswordForm(values, errors))
+	}
+
+	if err := SetPassword(kit.Request.Context(), &user, values.Password); err != nil {
+		return err
+	}
+
+	return kit.Redirect(http.StatusSeeOther, "/login")
+}
+
+// createPasswordResetToken signs a short lived token for the given user.
+// The token ID is derived from the current password hash, which makes the
+// token single use: once the password changed the ID no longer matches.
+func createPasswordResetToken(user User) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        passwordFingerprint(user.PasswordHash),
+		Subject:   fmt.Sprint(user.ID),
+		Audience:  jwt.ClaimStrings{passwordResetAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(conf.Get().Auth.PasswordResetExpiryInHours))),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(conf.Get().Secret))
+}
+
+func userFromPasswordResetToken(ctx context.Context, tokenStr string) (User, error) {
+	var user User
+	errInvalid := fmt.Errorf("invalid or expired password reset link")
+	if len(tokenStr) == 0 {
+		return user, errInvalid
+	}
+
+	token, err := jwt.ParseWithClaims(
+		tokenStr, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
+			return []byte(conf.Get().Secret), nil
+		},
+		jwt.WithLeeway(5*time.Second),
+		jwt.WithAudience(passwordResetAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil || !token.Valid {
+		return user, errInvalid
+	}
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok {
+		return user, errInvalid
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return user, errInvalid
+	}
+	if err := db.Get().WithContext(ctx).Find(&user, userID).Error; err != nil {
+		return user, err
+	}
+	if user.ID == 0 || claims.ID != passwordFingerprint(user.PasswordHash) {
+		return user, errInvalid
+	}
+	return user, nil
+}
+
+func passwordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(sum[:8])
+}
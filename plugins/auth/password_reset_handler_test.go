@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/dbtest"
+	"smartquiz/pkg/ratelimit"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordResetToken(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("Old-Passw0rd"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := User{Email: "alice@example.com", PasswordHash: string(hash)}
+	database.Create(&user)
+	b := newBrowser(t)
+
+	reset := func(token string) bool {
+		form := url.Values{"token": {token}, "password": {"New-Passw0rd"}, "passwordConfirm": {"New-Passw0rd"}}
+		w := b.do(HandleResetPasswordCreate, http.MethodPost, "/password/reset", form)
+		return w.Code == http.StatusSeeOther
+	}
+
+	token, err := createPasswordResetToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reset(token) {
+		t.Fatal("expected the password to be reset")
+	}
+	user = reloadUser(t, database, user)
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("New-Passw0rd")) != nil {
+		t.Fatal("expected the new password to be set")
+	}
+	if reset(token) {
+		t.Error("expected the token to be single use")
+	}
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        passwordFingerprint(user.PasswordHash),
+		Subject:   fmt.Sprint(user.ID),
+		Audience:  jwt.ClaimStrings{passwordResetAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+	tokenStr, err := expired.SignedString([]byte(conf.Get().Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset(tokenStr) {
+		t.Error("expected an expired token to be rejected")
+	}
+
+	verification, err := createVerificationToken(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset(verification) {
+		t.Error("expected an email verification token to be rejected")
+	}
+}
+
+func TestEmailVerifyAudience(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	user := User{Email: "alice@example.com", PasswordHash: "x"}
+	database.Create(&user)
+	b := newBrowser(t)
+
+	verify := func(token string) bool {
+		b.do(HandleEmailVerify, http.MethodGet, "/email/verify?token="+url.QueryEscape(token), nil)
+		return reloadUser(t, database, user).EmailVerifiedAt.Valid
+	}
+
+	reset, err := createPasswordResetToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verify(reset) {
+		t.Fatal("expected a password reset token to be rejected")
+	}
+	token, err := createVerificationToken(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify(token) {
+		t.Error("expected the verification token to verify the email")
+	}
+}
+
+func TestForgotPasswordRateLimit(t *testing.T) {
+	dbtest.New(t)
+	setupSessions(t)
+	previousEmail, previousIP := resetEmailLimiter, resetIPLimiter
+	t.Cleanup(func() { resetEmailLimiter, resetIPLimiter = previousEmail, previousIP })
+
+	// forgot reports whether the request was accepted, and fails when
+	// it wasn't rejected by the rate limit either.
+	forgot := func(ip, email string) bool {
+		b := newBrowser(t)
+		b.ip = ip
+		w := b.do(HandleForgotPasswordCreate, http.MethodPost, "/password/forgot", url.Values{"email": {email}})
+		body := w.Body.String()
+		if strings.Contains(body, "we have sent a link") {
+			return true
+		}
+		if !strings.Contains(body, "too many password reset requests") {
+			t.Fatalf("unexpected response: %s", body)
+		}
+		return false
+	}
+
+	tests := []struct {
+		name     string
+		requests func(i int) (ip, email string)
+		allowed  int
+	}{
+		{"same email", func(i int) (string, string) {
+			return fmt.Sprintf("192.0.2.%d", i), "alice@example.com"
+		}, 3},
+		{"same IP", func(i int) (string, string) {
+			return "192.0.2.1", fmt.Sprintf("user%d@example.com", i)
+		}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetEmailLimiter = ratelimit.New(3, time.Hour)
+			resetIPLimiter = ratelimit.New(10, time.Hour)
+			for i := 0; i <= tt.allowed; i++ {
+				ip, email := tt.requests(i)
+				if got := forgot(ip, email); got != (i < tt.allowed) {
+					t.Fatalf("request %d: got allowed %v", i+1, got)
+				}
+			}
+		})
+	}
+}
@@ -25,7 +25,7 @@ func HandleProfileShow(kit *kit.Kit) error {
 	auth := kit.Auth().(Auth)
 
 	var user User
-	if err := db.Get().First(&user, auth.UserID).Error; err != nil {
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
 		return err
 	}
 
@@ -36,7 +36,23 @@ func HandleProfileShow(kit *kit.Kit) error {
 		Email:     user.Email,
 	}
 
-	return kit.Render(ProfileShow(formValues))
+	tokens, err := tokenListData(kit.Request.Context(), user.ID, "")
+	if err != nil {
+		return err
+	}
+
+	sessions, err := sessionListData(kit, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return kit.Render(ProfileShow(ProfilePageData{
+		FormValues:       formValues,
+		Tokens:           tokens,
+		Sessions:         sessions,
+		TwoFactorEnabled: user.TwoFactorEnabled(),
+		SingleSignOn:     len(user.OIDCSubject) > 0 && oidcConfigFromConf().Enabled(),
+	}))
 }
 
 func HandleProfileUpdate(kit *kit.Kit) error {
@@ -50,7 +66,7 @@ func HandleProfileUpdate(kit *kit.Kit) error {
 	if auth.UserID != values.ID {
 		return fmt.Errorf("unauthorized request for profile %d", values.ID)
 	}
-	err := db.Get().Model(&User{}).
+	err := db.Get().WithContext(kit.Request.Context()).Model(&User{}).
 		Where("id = ?", auth.UserID).
 		Updates(&User{
 			FirstName: values.FirstName,
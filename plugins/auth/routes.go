@@ -13,11 +13,18 @@ func InitializeRoutes(router chi.Router) {
 
 	router.Get("/email/verify", kit.Handler(HandleEmailVerify))
 	router.Post("/resend-email-verification", kit.Handler(HandleResendVerificationCode))
+	router.Get("/password/forgot", kit.Handler(HandleForgotPasswordIndex))
+	router.Post("/password/forgot", kit.Handler(HandleForgotPasswordCreate))
+	router.Get("/password/reset", kit.Handler(HandleResetPasswordIndex))
+	router.Post("/password/reset", kit.Handler(HandleResetPasswordCreate))
 
 	router.Group(func(auth chi.Router) {
 		auth.Use(kit.WithAuthentication(authConfig, false))
 		auth.Get("/login", kit.Handler(HandleLoginIndex))
 		auth.Post("/login", kit.Handler(HandleLoginCreate))
+		auth.Post("/login/2fa", kit.Handler(HandleTwoFactorLoginCreate))
+		auth.Get("/login/oidc", kit.Handler(HandleOIDCLogin))
+		auth.Get("/login/oidc/callback", kit.Handler(HandleOIDCCallback))
 		auth.Delete("/logout", kit.Handler(HandleLoginDelete))
 
 		auth.Get("/signup", kit.Handler(HandleSignupIndex))
@@ -29,5 +36,16 @@ func InitializeRoutes(router chi.Router) {
 		auth.Use(kit.WithAuthentication(authConfig, true))
 		auth.Get("/profile", kit.Handler(HandleProfileShow))
 		auth.Put("/profile", kit.Handler(HandleProfileUpdate))
+		auth.Delete("/profile/sessions", kit.Handler(HandleSessionDeleteOthers))
+		auth.Delete("/profile/sessions/{id}", kit.Handler(HandleSessionDelete))
+		auth.Get("/profile/2fa", kit.Handler(HandleTwoFactorShow))
+		auth.Post("/profile/2fa", kit.Handler(HandleTwoFactorEnable))
+		auth.Post("/profile/2fa/disable", kit.Handler(HandleTwoFactorDisable))
+		auth.Post("/profile/tokens", kit.Handler(HandleTokenCreate))
+		auth.Delete("/profile/tokens/{id}", kit.Handler(HandleTokenDelete))
+		auth.Get("/profile/export", kit.Handler(HandleAccountExport))
+		auth.Post("/profile/delete", kit.Handler(HandleAccountDelete))
+		auth.Post("/profile/delete/oidc", kit.Handler(HandleAccountDeleteOIDC))
+		auth.Post("/impersonation/stop", kit.Handler(HandleImpersonationStop))
 	})
 }
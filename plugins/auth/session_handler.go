@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+)
+
+// SessionListData holds the active sessions shown on the profile page.
+type SessionListData struct {
+	Sessions     []Session
+	CurrentToken string
+}
+
+func HandleSessionDelete(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	id, err := strconv.Atoi(chi.URLParam(kit.Request, "id"))
+	if err != nil {
+		return err
+	}
+	err = db.Get().WithContext(kit.Request.Context()).Delete(&Session{}, "id = ? AND user_id = ?", id, auth.UserID).Error
+	if err != nil {
+		return err
+	}
+	return renderSessionSection(kit, auth.UserID)
+}
+
+// HandleSessionDeleteOthers logs the user out on every device except
+// the one making the request.
+func HandleSessionDeleteOthers(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	err := db.Get().WithContext(kit.Request.Context()).
+		Delete(&Session{}, "user_id = ? AND token <> ?", auth.UserID, currentSessionToken(kit)).Error
+	if err != nil {
+		return err
+	}
+	return renderSessionSection(kit, auth.UserID)
+}
+
+func renderSessionSection(kit *kit.Kit, userID uint) error {
+	data, err := sessionListData(kit, userID)
+	if err != nil {
+		return err
+	}
+	return kit.Render(SessionSection(data))
+}
+
+func sessionListData(kit *kit.Kit, userID uint) (SessionListData, error) {
+	data := SessionListData{CurrentToken: currentSessionToken(kit)}
+	err := db.Get().WithContext(kit.Request.Context()).
+		Order("created_at desc").
+		Find(&data.Sessions, "user_id = ? AND expires_at > ?", userID, time.Now()).Error
+	return data, err
+}
+
+func currentSessionToken(kit *kit.Kit) string {
+	token, _ := kit.GetSession(userSessionName).Values["sessionToken"].(string)
+	return token
+}
+
+// CleanupExpiredSessions deletes expired and logged out sessions every
+// interval until the context is cancelled.
+func CleanupExpiredSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res := db.Get().Unscoped().Delete(&Session{}, "expires_at <= ? OR deleted_at IS NOT NULL", time.Now())
+		if res.Error != nil {
+			slog.Error("failed to delete expired sessions", "err", res.Error)
+		} else if res.RowsAffected > 0 {
+			slog.Info("deleted expired sessions", "count", res.RowsAffected)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
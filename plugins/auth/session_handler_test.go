@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// withSessionID adds the id route parameter that chi would have
+// parsed from the URL.
+func withSessionID(h kit.HandlerFunc, id uint) kit.HandlerFunc {
+	return func(k *kit.Kit) error {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", fmt.Sprint(id))
+		k.Request = k.Request.WithContext(context.WithValue(k.Request.Context(), chi.RouteCtxKey, rctx))
+		return h(k)
+	}
+}
+
+// loggedInBrowser returns a browser logged in as the user with the
+// session token.
+func loggedInBrowser(t *testing.T, user User, token string) *browser {
+	t.Helper()
+	b := newBrowser(t)
+	b.auth = &Auth{UserID: user.ID, Email: user.Email, LoggedIn: true}
+	w := httptest.NewRecorder()
+	k := &kit.Kit{Response: w, Request: b.request(http.MethodGet, "/", nil)}
+	sess := k.GetSession(userSessionName)
+	sess.Values["sessionToken"] = token
+	if err := sess.Save(k.Request, w); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range w.Result().Cookies() {
+		b.cookies[c.Name] = c
+	}
+	return b
+}
+
+func insertSession(t *testing.T, database *gorm.DB, user User, token string, expiresAt time.Time) Session {
+	t.Helper()
+	session := Session{UserID: user.ID, Token: token, ExpiresAt: expiresAt}
+	if err := database.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// sessionTokens returns the tokens of the sessions of the user that
+// weren't deleted.
+func sessionTokens(t *testing.T, database *gorm.DB, user User) map[string]bool {
+	t.Helper()
+	var sessions []Session
+	if err := database.Find(&sessions, "user_id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokens := make(map[string]bool)
+	for _, s := range sessions {
+		tokens[s.Token] = true
+	}
+	return tokens
+}
+
+func TestSessionDelete(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	alice := User{Email: "alice@example.com", PasswordHash: "x"}
+	bob := User{Email: "bob@example.com", PasswordHash: "x"}
+	database.Create(&alice)
+	database.Create(&bob)
+	expiresAt := time.Now().Add(time.Hour)
+	insertSession(t, database, alice, "alice-laptop", expiresAt)
+	phone := insertSession(t, database, alice, "alice-phone", expiresAt)
+	bobs := insertSession(t, database, bob, "bob-laptop", expiresAt)
+	b := loggedInBrowser(t, alice, "alice-laptop")
+
+	w := b.do(withSessionID(HandleSessionDelete, phone.ID), http.MethodDelete, "/profile/sessions", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200", w.Code)
+	}
+	if got := sessionTokens(t, database, alice); len(got) != 1 || !got["alice-laptop"] {
+		t.Errorf("expected only the phone session to be revoked, got %v", got)
+	}
+
+	b.do(withSessionID(HandleSessionDelete, bobs.ID), http.MethodDelete, "/profile/sessions", nil)
+	if got := sessionTokens(t, database, bob); !got["bob-laptop"] {
+		t.Error("expected the session of another user to be kept")
+	}
+}
+
+func TestSessionDeleteOthers(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	alice := User{Email: "alice@example.com", PasswordHash: "x"}
+	bob := User{Email: "bob@example.com", PasswordHash: "x"}
+	database.Create(&alice)
+	database.Create(&bob)
+	expiresAt := time.Now().Add(time.Hour)
+	for _, token := range []string{"alice-laptop", "alice-phone", "alice-tablet"} {
+		insertSession(t, database, alice, token, expiresAt)
+	}
+	insertSession(t, database, bob, "bob-laptop", expiresAt)
+	b := loggedInBrowser(t, alice, "alice-laptop")
+
+	b.do(HandleSessionDeleteOthers, http.MethodDelete, "/profile/sessions", nil)
+	if got := sessionTokens(t, database, alice); len(got) != 1 || !got["alice-laptop"] {
+		t.Errorf("expected only the current session to be kept, got %v", got)
+	}
+	if got := sessionTokens(t, database, bob); !got["bob-laptop"] {
+		t.Error("expected the sessions of other users to be kept")
+	}
+}
+
+func TestCleanupExpiredSessions(t *testing.T) {
+	database := dbtest.New(t)
+	user := User{Email: "alice@example.com", PasswordHash: "x"}
+	database.Create(&user)
+	now := time.Now()
+	insertSession(t, database, user, "active", now.Add(time.Hour))
+	insertSession(t, database, user, "expired", now.Add(-time.Minute))
+	loggedOut := insertSession(t, database, user, "logged-out", now.Add(time.Hour))
+	database.Delete(&loggedOut)
+
+	// The first cleanup runs before the context is checked.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	CleanupExpiredSessions(ctx, time.Hour)
+
+	var sessions []Session
+	if err := database.Unscoped().Find(&sessions, "user_id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Token != "active" {
+		t.Errorf("expected only the active session to be left, got %+v", sessions)
+	}
+}
@@ -1,19 +1,24 @@
 package auth
 
 import (
+	"smartquiz/app/conf"
 	"smartquiz/app/db"
+	"smartquiz/pkg/tracing"
 	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
-	"github.com/anthdm/superkit/event"
 	"github.com/anthdm/superkit/kit"
 	v "github.com/anthdm/superkit/validate"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const signupSessionName = "signup-session"
+
+// Verification tokens are signed with the same secret as the password
+// reset tokens, the audience keeps them apart.
+const emailVerificationAudience = "email-verify"
+
 var signupSchema = v.Schema{
 	"email": v.Rules(v.Email),
 	"password": v.Rules(
@@ -40,7 +45,7 @@ func HandleSignupCreate(kit *kit.Kit) error {
 		errors.Add("passwordConfirm", "passwords do not match")
 		return kit.Render(SignupForm(values, errors))
 	}
-	user, err := createUserFromFormValues(values)
+	user, err := createUserFromFormValues(db.Get().WithContext(kit.Request.Context()), values)
 	if err != nil {
 		return err
 	}
@@ -48,22 +53,37 @@ func HandleSignupCreate(kit *kit.Kit) error {
 	if err != nil {
 		return err
 	}
-	event.Emit(UserSignupEvent, UserWithVerificationToken{
+	tracing.Emit(kit.Request.Context(), UserSignupEvent, UserWithVerificationToken{
 		Token: token,
 		User:  user,
 	})
+	if err := bindVerificationSession(kit, user); err != nil {
+		return err
+	}
 	return kit.Render(ConfirmEmail(user))
 }
 
+// bindVerificationSession remembers the user that may request a new
+// verification email from this browser. The user ID is never taken from
+// the request, so verification emails can't be triggered for other users.
+func bindVerificationSession(kit *kit.Kit, user User) error {
+	sess := kit.GetSession(signupSessionName)
+	sess.Values["userID"] = user.ID
+	return sess.Save(kit.Request, kit.Response)
+}
+
 func HandleResendVerificationCode(kit *kit.Kit) error {
-	idstr := kit.FormValue("userID")
-	id, err := strconv.Atoi(idstr)
-	if err != nil {
-		return err
+	sess := kit.GetSession(signupSessionName)
+	id, ok := sess.Values["userID"].(uint)
+	if !ok {
+		return kit.Text(http.StatusOK, "Your session expired, please log in again to request a new verification email")
+	}
+	if ok, _ := resendLimiter.Allow(fmt.Sprint(id)); !ok {
+		return kit.Text(http.StatusTooManyRequests, "Please wait a few minutes before requesting another verification email")
 	}
 
 	var user User
-	if err = db.Get().First(&user, id).Error; err != nil {
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, id).Error; err != nil {
 		return kit.Text(http.StatusOK, "An unexpected error occured")
 	}
 
@@ -71,12 +91,12 @@ func HandleResendVerificationCode(kit *kit.Kit) error {
 		return kit.Text(http.StatusOK, "Email already verified!")
 	}
 
-	token, err := createVerificationToken(uint(id))
+	token, err := createVerificationToken(id)
 	if err != nil {
 		return kit.Text(http.StatusOK, "An unexpected error occured")
 	}
 
-	event.Emit(ResendVerificationEvent, UserWithVerificationToken{
+	tracing.Emit(kit.Request.Context(), ResendVerificationEvent, UserWithVerificationToken{
 		User:  user,
 		Token: token,
 	})
@@ -87,18 +107,13 @@ func HandleResendVerificationCode(kit *kit.Kit) error {
 }
 
 func createVerificationToken(userID uint) (string, error) {
-	expiryStr := kit.Getenv("SUPERKIT_AUTH_EMAIL_VERIFICATION_EXPIRY_IN_HOURS", "1")
-	expiry, err := strconv.Atoi(expiryStr)
-	if err != nil {
-		expiry = 1
-	}
-
 	claims := jwt.RegisteredClaims{
 		Subject:   fmt.Sprint(userID),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiry))),
+		Audience:  jwt.ClaimStrings{emailVerificationAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(conf.Get().Auth.EmailVerificationExpiryInHours))),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(os.Getenv("SUPERKIT_SECRET")))
+	return token.SignedString([]byte(conf.Get().Secret))
 }
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartquiz/app/db"
+	"smartquiz/pkg/logging"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	apiTokenPrefix = "sq_"
+	// Last used timestamps are only written once per interval so
+	// busy scripts don't cause a write on every request.
+	apiTokenTouchInterval = time.Minute
+)
+
+var tokenSchema = v.Schema{
+	"name": v.Rules(v.Min(2), v.Max(50)),
+}
+
+type TokenFormValues struct {
+	Name          string `form:"name"`
+	ScopeRead     bool   `form:"scopeRead"`
+	ScopeWrite    bool   `form:"scopeWrite"`
+	ScopeUpload   bool   `form:"scopeUpload"`
+	ExpiresInDays int    `form:"expiresInDays"`
+}
+
+// TokenListData holds the tokens shown on the profile page. NewToken
+// is only set right after a token has been created.
+type TokenListData struct {
+	Tokens   []APIToken
+	NewToken string
+}
+
+func HandleTokenCreate(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+
+	var values TokenFormValues
+	errors, ok := v.Request(kit.Request, &values, tokenSchema)
+	var scopes []string
+	if values.ScopeRead {
+		scopes = append(scopes, ScopeRead)
+	}
+	if values.ScopeWrite {
+		scopes = append(scopes, ScopeWrite)
+	}
+	if values.ScopeUpload {
+		scopes = append(scopes, ScopeUpload)
+	}
+	if len(scopes) == 0 {
+		errors.Add("scopes", "select at least one scope")
+		ok = false
+	}
+	// Tokens would outlive the impersonation.
+	if auth.Impersonating() {
+		errors.Add("name", "API tokens can't be created while impersonating a user")
+		ok = false
+	}
+	if isDemoAccount(auth.Email) {
+		errors.Add("name", demoAccountMessage)
+		ok = false
+	}
+	if values.ExpiresInDays < 0 {
+		errors.Add("expiresInDays", "must not be negative")
+		ok = false
+	}
+	if !ok {
+		data, err := tokenListData(kit.Request.Context(), auth.UserID, "")
+		if err != nil {
+			return err
+		}
+		return kit.Render(TokenSection(data, values, errors))
+	}
+
+	plain, err := generateAPIToken()
+	if err != nil {
+		return err
+	}
+	token := APIToken{
+		UserID:    auth.UserID,
+		Name:      values.Name,
+		TokenHash: hashAPIToken(plain),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if values.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, values.ExpiresInDays)
+		token.ExpiresAt = sql.NullTime{Time: expiresAt, Valid: true}
+	}
+	if err := db.Get().WithContext(kit.Request.Context()).Create(&token).Error; err != nil {
+		return err
+	}
+
+	data, err := tokenListData(kit.Request.Context(), auth.UserID, plain)
+	if err != nil {
+		return err
+	}
+	return kit.Render(TokenSection(data, TokenFormValues{}, v.Errors{}))
+}
+
+func HandleTokenDelete(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	id, err := strconv.Atoi(chi.URLParam(kit.Request, "id"))
+	if err != nil {
+		return err
+	}
+	err = db.Get().WithContext(kit.Request.Context()).Delete(&APIToken{}, "id = ? AND user_id = ?", id, auth.UserID).Error
+	if err != nil {
+		return err
+	}
+	data, err := tokenListData(kit.Request.Context(), auth.UserID, "")
+	if err != nil {
+		return err
+	}
+	return kit.Render(TokenSection(data, TokenFormValues{}, v.Errors{}))
+}
+
+func tokenListData(ctx context.Context, userID uint, newToken string) (TokenListData, error) {
+	data := TokenListData{NewToken: newToken}
+	err := db.Get().WithContext(ctx).Order("id desc").Find(&data.Tokens, "user_id = ?", userID).Error
+	return data, err
+}
+
+// AuthenticateAPIToken authenticates the request with the API token in
+// the "Authorization: Bearer" header. It can be used as the AuthFunc of
+// a kit.AuthenticationConfig.
+func AuthenticateAPIToken(kit *kit.Kit) (kit.Auth, error) {
+	auth := Auth{}
+	plain, ok := bearerToken(kit.Request)
+	if !ok {
+		return auth, nil
+	}
+
+	var token APIToken
+	err := db.Get().WithContext(kit.Request.Context()).
+		Preload("User").
+		Find(&token, "token_hash = ?", hashAPIToken(plain)).Error
+	// Accounts scheduled for deletion are logged out everywhere, their
+	// tokens stop working until the user logs in again.
+	if err != nil || token.ID == 0 || token.Expired() || token.User.Disabled() ||
+		token.User.DeletionRequestedAt.Valid {
+		return auth, nil
+	}
+
+	if !token.LastUsedAt.Valid || time.Since(token.LastUsedAt.Time) > apiTokenTouchInterval {
+		err = db.Get().WithContext(kit.Request.Context()).Model(&token).
+			Update("last_used_at", sql.NullTime{Time: time.Now(), Valid: true}).Error
+		if err != nil {
+			return auth, err
+		}
+	}
+
+	logging.Add(kit.Request.Context(), "user_id", token.User.ID, "api_token_id", token.ID)
+	return Auth{
+		LoggedIn: true,
+		UserID:   token.User.ID,
+		Email:    token.User.Email,
+		TokenID:  token.ID,
+		Scopes:   token.ScopeList(),
+		Role:     token.User.Role,
+	}, nil
+}
+
+// AuthenticateAPIRequest uses the API token when the request carries
+// an Authorization header and falls back to the session cookie otherwise.
+func AuthenticateAPIRequest(kit *kit.Kit) (kit.Auth, error) {
+	if _, ok := bearerToken(kit.Request); ok {
+		return AuthenticateAPIToken(kit)
+	}
+	return AuthenticateUser(kit)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || len(token) == 0 {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// API tokens are long random strings, a plain SHA-256 is enough
+// and lets us look tokens up by their hash.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"smartquiz/app/conf"
+	"smartquiz/app/db"
+
+	"github.com/anthdm/superkit/kit"
+	v "github.com/anthdm/superkit/validate"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorSessionName = "two-factor"
+	// How long a user has to enter the code after the password was accepted.
+	twoFactorLoginTimeout = 5 * time.Minute
+	recoveryCodeCount     = 10
+	totpIssuer            = "smartquiz"
+	// The period of the codes generated by totp.GenerateCode.
+	totpPeriod = 30
+)
+
+var twoFactorCodeSchema = v.Schema{
+	"code": v.Rules(v.Required),
+}
+
+var passwordConfirmSchema = v.Schema{
+	"password": v.Rules(v.Required),
+}
+
+type TwoFactorFormValues struct {
+	Code string `form:"code"`
+}
+
+type TwoFactorDisableFormValues struct {
+	Password string `form:"password"`
+}
+
+// TwoFactorPageData is used by the enrollment page. QRCode is a PNG
+// data URI of the otpauth:// URL, rendered locally so the secret never
+// leaves the server.
+type TwoFactorPageData struct {
+	Enabled       bool
+	Secret        string
+	QRCode        string
+	RecoveryCodes []string
+}
+
+// startTwoFactorLogin remembers that the user entered a correct password
+// and now has to provide a TOTP or recovery code.
+func startTwoFactorLogin(kit *kit.Kit, user User) error {
+	sess := kit.GetSession(twoFactorSessionName)
+	sess.Values["userID"] = user.ID
+	sess.Values["expiresAt"] = time.Now().Add(twoFactorLoginTimeout).Unix()
+	return sess.Save(kit.Request, kit.Response)
+}
+
+func HandleTwoFactorLoginCreate(kit *kit.Kit) error {
+	var values TwoFactorFormValues
+	errors, ok := v.Request(kit.Request, &values, twoFactorCodeSchema)
+	if !ok {
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+
+	sess := kit.GetSession(twoFactorSessionName)
+	userID, _ := sess.Values["userID"].(uint)
+	expiresAt, _ := sess.Values["expiresAt"].(int64)
+	if userID == 0 || time.Now().Unix() > expiresAt {
+		errors.Add("code", "your login expired, please enter your password again")
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, userID).Error; err != nil {
+		return err
+	}
+	guard := getLoginGuard()
+	if wait := guard.check(kit.Request, user.Email); wait > 0 {
+		errors.Add("code", tooManyAttemptsMessage(wait))
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+	valid, err := checkSecondFactor(kit.Request.Context(), user, values.Code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		guard.fail(kit.Request, user.Email, "invalid two-factor code")
+		errors.Add("code", "invalid code")
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+	guard.succeed(user.Email)
+
+	sess.Values = map[any]any{}
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+	// The account may have changed since the password was accepted. A
+	// deletion requested in the meantime isn't cancelled by this login.
+	startedAt := time.Unix(expiresAt, 0).Add(-twoFactorLoginTimeout)
+	if user.Disabled() {
+		errors.Add("code", accountDisabledMessage)
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+	if user.DeletionRequestedAt.Valid && !user.DeletionRequestedAt.Time.Before(startedAt) {
+		errors.Add("code", "your account was scheduled for deletion, please log in again")
+		return kit.Render(TwoFactorLoginForm(errors))
+	}
+	if err := createSession(kit, user); err != nil {
+		return err
+	}
+	redirectURL := conf.Get().Auth.RedirectAfterLogin
+	return kit.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// checkSecondFactor accepts either a current TOTP code or one of the
+// unused recovery codes, which is used up in the process. A TOTP code
+// is only accepted once, and no code older than the last one used.
+func checkSecondFactor(ctx context.Context, user User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := totpStep(code, user.TOTPSecret, time.Now()); ok {
+		// Conditional, so two requests with the same code can't both
+		// pass.
+		res := db.Get().WithContext(ctx).Model(&User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return res.RowsAffected == 1, res.Error
+	}
+
+	var recoveryCodes []RecoveryCode
+	err := db.Get().WithContext(ctx).Find(&recoveryCodes, "user_id = ? AND used_at IS NULL", user.ID).Error
+	if err != nil {
+		return false, err
+	}
+	normalized := normalizeRecoveryCode(code)
+	for _, rc := range recoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(normalized)) == nil {
+			res := db.Get().WithContext(ctx).Model(&RecoveryCode{}).
+				Where("id = ? AND used_at IS NULL", rc.ID).
+				Update("used_at", sql.NullTime{Time: time.Now(), Valid: true})
+			return res.RowsAffected == 1, res.Error
+		}
+	}
+	return false, nil
+}
+
+// totpStep returns the time step of the code if it is valid at now,
+// allowing for one step of clock skew like totp.Validate.
+func totpStep(code, secret string, now time.Time) (int64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCode(secret, t)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return t.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+func HandleTwoFactorShow(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
+		return err
+	}
+	if user.TwoFactorEnabled() {
+		return kit.Render(TwoFactorShow(TwoFactorPageData{Enabled: true}, v.Errors{}))
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return err
+	}
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+
+	// The secret is only stored on the user once a code generated from
+	// it has been confirmed.
+	sess := kit.GetSession(twoFactorSessionName)
+	sess.Values["enrollSecret"] = key.Secret()
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+
+	return kit.Render(TwoFactorShow(TwoFactorPageData{
+		Secret: key.Secret(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()),
+	}, v.Errors{}))
+}
+
+func HandleTwoFactorEnable(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var values TwoFactorFormValues
+	errors, ok := v.Request(kit.Request, &values, twoFactorCodeSchema)
+	if !ok {
+		return kit.Render(TwoFactorEnrollForm(errors))
+	}
+	if isDemoAccount(auth.Email) {
+		errors.Add("code", demoAccountMessage)
+		return kit.Render(TwoFactorEnrollForm(errors))
+	}
+
+	sess := kit.GetSession(twoFactorSessionName)
+	secret, _ := sess.Values["enrollSecret"].(string)
+	if len(secret) == 0 {
+		errors.Add("code", "the setup expired, please reload the page")
+		return kit.Render(TwoFactorEnrollForm(errors))
+	}
+	step, ok := totpStep(strings.TrimSpace(values.Code), secret, time.Now())
+	if !ok {
+		errors.Add("code", "invalid code, check the time on your device and try again")
+		return kit.Render(TwoFactorEnrollForm(errors))
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	err = db.Get().WithContext(kit.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", auth.UserID).Updates(map[string]any{
+			"totp_secret":     secret,
+			"totp_enabled_at": sql.NullTime{Time: time.Now(), Valid: true},
+			"totp_last_step":  step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&RecoveryCode{}, "user_id = ?", auth.UserID).Error; err != nil {
+			return err
+		}
+		for _, code := range codes {
+			hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			rc := RecoveryCode{UserID: auth.UserID, CodeHash: string(hash)}
+			if err := tx.Create(&rc).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(sess.Values, "enrollSecret")
+	if err := sess.Save(kit.Request, kit.Response); err != nil {
+		return err
+	}
+	return kit.Render(TwoFactorRecoveryCodes(codes))
+}
+
+func HandleTwoFactorDisable(kit *kit.Kit) error {
+	auth := kit.Auth().(Auth)
+	var values TwoFactorDisableFormValues
+	errors, ok := v.Request(kit.Request, &values, passwordConfirmSchema)
+	if !ok {
+		return kit.Render(TwoFactorDisableForm(errors))
+	}
+
+	var user User
+	if err := db.Get().WithContext(kit.Request.Context()).First(&user, auth.UserID).Error; err != nil {
+		return err
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(values.Password))
+	if err != nil {
+		errors.Add("password", "invalid password")
+		return kit.Render(TwoFactorDisableForm(errors))
+	}
+
+	err = db.Get().WithContext(kit.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"totp_secret":     "",
+			"totp_enabled_at": sql.NullTime{},
+			"totp_last_step":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&RecoveryCode{}, "user_id = ?", user.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	return kit.Redirect(http.StatusSeeOther, "/profile/2fa")
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"smartquiz/app/dbtest"
+
+	"github.com/anthdm/superkit/kit"
+	"github.com/gorilla/sessions"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// setupSessions initializes the cookie store of kit, kit.Setup reads
+// the secret from the .env file in the working directory.
+func setupSessions(t *testing.T) {
+	t.Helper()
+	const secret = "a-secret-that-is-long-enough-for-superkit"
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("SUPERKIT_SECRET="+secret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SUPERKIT_SECRET", secret)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	kit.Setup()
+}
+
+// browser calls handlers the way a browser would, keeping the cookies
+// set by earlier responses. Requests are made as the logged in user
+// when auth is set.
+type browser struct {
+	t       *testing.T
+	ip      string
+	auth    *Auth
+	cookies map[string]*http.Cookie
+}
+
+func newBrowser(t *testing.T) *browser {
+	return &browser{t: t, ip: "192.0.2.1", cookies: make(map[string]*http.Cookie)}
+}
+
+func (b *browser) request(method, target string, form url.Values) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.RemoteAddr = b.ip + ":1234"
+	for _, c := range b.cookies {
+		r.AddCookie(c)
+	}
+	if b.auth != nil {
+		r = r.WithContext(context.WithValue(r.Context(), kit.AuthKey{}, *b.auth))
+	}
+	return r
+}
+
+func (b *browser) do(h kit.HandlerFunc, method, target string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	w := httptest.NewRecorder()
+	if err := h(&kit.Kit{Response: w, Request: b.request(method, target, form)}); err != nil {
+		b.t.Fatal(err)
+	}
+	for _, c := range w.Result().Cookies() {
+		b.cookies[c.Name] = c
+	}
+	return w
+}
+
+func (b *browser) session(name string) *sessions.Session {
+	k := &kit.Kit{Response: httptest.NewRecorder(), Request: b.request(http.MethodGet, "/", nil)}
+	return k.GetSession(name)
+}
+
+func reloadUser(t *testing.T, database *gorm.DB, user User) User {
+	t.Helper()
+	var reloaded User
+	if err := database.First(&reloaded, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return reloaded
+}
+
+var recoveryCodePattern = regexp.MustCompile(`\b[a-z2-7]{5}-[a-z2-7]{5}\b`)
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	user := User{Email: "alice@example.com", PasswordHash: "x"}
+	database.Create(&user)
+	b := newBrowser(t)
+	b.auth = &Auth{UserID: user.ID, Email: user.Email, LoggedIn: true}
+
+	b.do(HandleTwoFactorShow, http.MethodGet, "/profile/2fa", nil)
+	secret, _ := b.session(twoFactorSessionName).Values["enrollSecret"].(string)
+	if secret == "" {
+		t.Fatal("expected the secret in the session")
+	}
+
+	b.do(HandleTwoFactorEnable, http.MethodPost, "/profile/2fa", url.Values{"code": {"000000"}})
+	if reloadUser(t, database, user).TwoFactorEnabled() {
+		t.Fatal("expected an invalid code to be rejected")
+	}
+
+	now := time.Now()
+	code, err := totp.GenerateCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := b.do(HandleTwoFactorEnable, http.MethodPost, "/profile/2fa", url.Values{"code": {code}})
+	user = reloadUser(t, database, user)
+	if !user.TwoFactorEnabled() || user.TOTPSecret != secret {
+		t.Fatal("expected two-factor authentication to be enabled")
+	}
+	if codes := recoveryCodePattern.FindAllString(w.Body.String(), -1); len(codes) != recoveryCodeCount {
+		t.Errorf("expected %d recovery codes on the page, got %d", recoveryCodeCount, len(codes))
+	}
+	var count int64
+	database.Model(&RecoveryCode{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != recoveryCodeCount {
+		t.Errorf("expected %d recovery codes, got %d", recoveryCodeCount, count)
+	}
+	if _, ok := b.session(twoFactorSessionName).Values["enrollSecret"]; ok {
+		t.Error("expected the secret to be removed from the session")
+	}
+
+	// The code confirming the enrollment can't be used to log in.
+	valid, err := checkSecondFactor(context.Background(), user, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("expected the enrollment code to be rejected")
+	}
+}
+
+func TestTOTPReplay(t *testing.T) {
+	database := dbtest.New(t)
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	user := User{
+		Email:         "alice@example.com",
+		PasswordHash:  "x",
+		TOTPSecret:    key.Secret(),
+		TOTPEnabledAt: sql.NullTime{Time: now, Valid: true},
+	}
+	database.Create(&user)
+	codeAt := func(t *testing.T, at time.Time) string {
+		code, err := totp.GenerateCode(key.Secret(), at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	period := totpPeriod * time.Second
+
+	tests := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"previous step", codeAt(t, now.Add(-period)), true},
+		{"current step", codeAt(t, now), true},
+		{"current step again", codeAt(t, now), false},
+		{"previous step after the current one", codeAt(t, now.Add(-period)), false},
+		{"too old", codeAt(t, now.Add(-3*period)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := checkSecondFactor(context.Background(), reloadUser(t, database, user), tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tt.valid {
+				t.Errorf("got valid %v, want %v", valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodeLogin(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("abcde23456"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser := func(email string) User {
+		user := User{
+			Email:         email,
+			PasswordHash:  "x",
+			TOTPSecret:    "JBSWY3DPEHPK3PXP",
+			TOTPEnabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}
+		database.Create(&user)
+		database.Create(&RecoveryCode{UserID: user.ID, CodeHash: string(hash)})
+		return user
+	}
+	// login enters the recovery code after the password was accepted
+	// and reports whether the user is logged in.
+	login := func(user User, changeAccount func()) bool {
+		b := newBrowser(t)
+		w := httptest.NewRecorder()
+		k := &kit.Kit{Response: w, Request: b.request(http.MethodPost, "/login", nil)}
+		if err := startTwoFactorLogin(k, user); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range w.Result().Cookies() {
+			b.cookies[c.Name] = c
+		}
+		if changeAccount != nil {
+			changeAccount()
+		}
+		b.do(HandleTwoFactorLoginCreate, http.MethodPost, "/login/2fa", url.Values{"code": {"ABCDE-23456"}})
+		var sessions int64
+		database.Model(&Session{}).Where("user_id = ?", user.ID).Count(&sessions)
+		return sessions > 0
+	}
+
+	user := newUser("alice@example.com")
+	if !login(user, nil) {
+		t.Fatal("expected the recovery code to log the user in")
+	}
+	var rc RecoveryCode
+	database.First(&rc, "user_id = ?", user.ID)
+	if !rc.UsedAt.Valid {
+		t.Error("expected the recovery code to be used up")
+	}
+	database.Where("user_id = ?", user.ID).Delete(&Session{})
+	if login(user, nil) {
+		t.Error("expected a used recovery code to be rejected")
+	}
+
+	disabled := newUser("disabled@example.com")
+	if login(disabled, func() { DisableUser(context.Background(), &disabled) }) {
+		t.Error("expected a user disabled during the login to be rejected")
+	}
+
+	leaving := newUser("leaving@example.com")
+	requestDeletion := func() {
+		database.Model(&leaving).Update("deletion_requested_at", sql.NullTime{Time: time.Now(), Valid: true})
+	}
+	if login(leaving, requestDeletion) {
+		t.Error("expected a deletion requested during the login to be kept")
+	}
+	if !reloadUser(t, database, leaving).DeletionRequestedAt.Valid {
+		t.Error("expected the deletion to stay scheduled")
+	}
+}
+
+func TestTwoFactorDisable(t *testing.T) {
+	database := dbtest.New(t)
+	setupSessions(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := User{
+		Email:         "alice@example.com",
+		PasswordHash:  string(hash),
+		TOTPSecret:    "JBSWY3DPEHPK3PXP",
+		TOTPEnabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+		TOTPLastStep:  time.Now().Unix() / totpPeriod,
+	}
+	database.Create(&user)
+	database.Create(&RecoveryCode{UserID: user.ID, CodeHash: "x"})
+	b := newBrowser(t)
+	b.auth = &Auth{UserID: user.ID, Email: user.Email, LoggedIn: true}
+
+	b.do(HandleTwoFactorDisable, http.MethodPost, "/profile/2fa/disable", url.Values{"password": {"wrong"}})
+	if !reloadUser(t, database, user).TwoFactorEnabled() {
+		t.Fatal("expected a wrong password to keep two-factor authentication")
+	}
+
+	w := b.do(HandleTwoFactorDisable, http.MethodPost, "/profile/2fa/disable", url.Values{"password": {"secret password"}})
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("got status %d, want 303", w.Code)
+	}
+	user = reloadUser(t, database, user)
+	if user.TwoFactorEnabled() || user.TOTPSecret != "" || user.TOTPLastStep != 0 {
+		t.Errorf("expected two-factor authentication to be disabled, got %+v", user)
+	}
+	var count int64
+	database.Unscoped().Model(&RecoveryCode{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the recovery codes to be deleted, got %d", count)
+	}
+}
@@ -1,8 +1,9 @@
 package auth
 
 import (
-	"smartquiz/app/db"
 	"database/sql"
+	"slices"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -13,6 +14,7 @@ import (
 const (
 	UserSignupEvent         = "auth.signup"
 	ResendVerificationEvent = "auth.resend.verification"
+	PasswordResetEvent      = "auth.password.reset"
 )
 
 // UserWithVerificationToken is a struct that will be sent over the
@@ -22,16 +24,52 @@ type UserWithVerificationToken struct {
 	Token string
 }
 
+// UserWithResetToken is a struct that will be sent over the
+// auth.password.reset event. It holds the User struct and the password
+// reset token string.
+type UserWithResetToken struct {
+	User  User
+	Token string
+}
+
 type Auth struct {
 	UserID   uint
 	Email    string
 	LoggedIn bool
+	// TokenID is set when the request was authenticated with an
+	// API token instead of the session cookie.
+	TokenID uint
+	Scopes  []string
+	Role    string
+	// ImpersonatorID is the admin that is logged in as this user.
+	ImpersonatorID uint
 }
 
 func (auth Auth) Check() bool {
 	return auth.LoggedIn
 }
 
+func (auth Auth) IsAdmin() bool {
+	return auth.Check() && auth.Role == RoleAdmin
+}
+
+func (auth Auth) Impersonating() bool {
+	return auth.ImpersonatorID != 0
+}
+
+// Can returns true if the user is allowed to act within the given scope.
+// Cookie sessions are not restricted, API tokens only have the scopes
+// they were created with.
+func (auth Auth) Can(scope string) bool {
+	if !auth.Check() {
+		return false
+	}
+	if auth.TokenID == 0 {
+		return true
+	}
+	return slices.Contains(auth.Scopes, scope)
+}
+
 type User struct {
 	gorm.Model
 
@@ -40,11 +78,43 @@ type User struct {
 	LastName        string
 	PasswordHash    string
 	EmailVerifiedAt sql.NullTime
+	TOTPSecret      string       `gorm:"column:totp_secret"`
+	TOTPEnabledAt   sql.NullTime `gorm:"column:totp_enabled_at"`
+	TOTPLastStep    int64        `gorm:"column:totp_last_step"` // time step of the last accepted code
+	OIDCSubject     string       `gorm:"column:oidc_subject"` // subject at the OIDC identity provider
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	// The account is purged once the grace period after
+	// DeletionRequestedAt is over.
+	DeletionRequestedAt sql.NullTime
+	Role                string
+	// Disabled users can't log in and their sessions and API tokens
+	// are rejected.
+	DisabledAt sql.NullTime
+	// DemoSeededAt is set on the account seeded as the demo account,
+	// see package seed. Only that account is reset by the demo.
+	DemoSeededAt sql.NullTime
+}
+
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+func (user User) TwoFactorEnabled() bool {
+	return user.TOTPEnabledAt.Valid
 }
 
-func createUserFromFormValues(values SignupFormValues) (User, error) {
+func (user User) IsAdmin() bool {
+	return user.Role == RoleAdmin
+}
+
+func (user User) Disabled() bool {
+	return user.DisabledAt.Valid
+}
+
+func createUserFromFormValues(tx *gorm.DB, values SignupFormValues) (User, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(values.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return User{}, err
@@ -54,8 +124,9 @@ func createUserFromFormValues(values SignupFormValues) (User, error) {
 		FirstName:    values.FirstName,
 		LastName:     values.LastName,
 		PasswordHash: string(hash),
+		Role:         RoleUser,
 	}
-	result := db.Get().Create(&user)
+	result := tx.Create(&user)
 	return user, result.Error
 }
 
@@ -69,4 +140,60 @@ type Session struct {
 	ExpiresAt time.Time
 	CreatedAt time.Time
 	User      User
+	// ImpersonatorID is set on sessions an admin opened to act as
+	// the user for support.
+	ImpersonatorID uint
+}
+
+// API token scopes
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeUpload = "upload"
+)
+
+// APIToken is a personal access token used by scripts and integrations
+// to authenticate with the JSON API. Only the SHA-256 hash of the token
+// is stored, the plain token is shown once after creation.
+type APIToken struct {
+	gorm.Model
+
+	UserID     uint
+	Name       string
+	TokenHash  string
+	Scopes     string
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	User       User
+}
+
+func (token APIToken) ScopeList() []string {
+	if len(token.Scopes) == 0 {
+		return nil
+	}
+	return strings.Split(token.Scopes, ",")
+}
+
+func (token APIToken) Expired() bool {
+	return token.ExpiresAt.Valid && token.ExpiresAt.Time.Before(time.Now())
+}
+
+// RecoveryCode is a single use code that can replace the TOTP code when
+// the user lost access to their authenticator app.
+type RecoveryCode struct {
+	gorm.Model
+
+	UserID   uint
+	CodeHash string
+	UsedAt   sql.NullTime
+}
+
+// LoginAttempt is the audit log entry of a failed login.
+type LoginAttempt struct {
+	ID        uint `gorm:"primarykey"`
+	Email     string
+	IPAddress string
+	UserAgent string
+	Reason    string
+	CreatedAt time.Time
 }